@@ -0,0 +1,69 @@
+package main
+
+// options applied when turning definitions into graph vertices
+type buildOptions struct {
+	lemmatize bool // map inflected definition words onto their headwords
+	phrases   bool // group definition words into phrase headwords, longest match first
+
+	// function words like "the" and "of" that would otherwise end up in every FVS
+	stopwords  map[string]bool
+	stopPolicy stopPolicy
+
+	// what happens to each class of undefined word, see undef.go
+	// remapping numbers or leaves leaves them as they are
+	numbers     undefAction
+	inflections undefAction
+	typos       undefAction
+	leaves      undefAction
+}
+
+// what is done with the stopwords when building the graph
+type stopPolicy int
+
+const (
+	normalStop    stopPolicy = iota // stopwords are ordinary words
+	primitiveStop                   // kept out of the graph and never expanded
+	knownStop                       // kept out of the graph and added to the solution
+)
+
+func (p stopPolicy) String() string {
+	switch p {
+	case primitiveStop:
+		return "primitive"
+	case knownStop:
+		return "known"
+	}
+	return "normal"
+}
+
+// common English function words, for when no stopword list is at hand
+var functionWords = map[string]bool{
+	"a": true, "an": true, "the": true, "of": true, "and": true, "or": true, "to": true,
+	"in": true, "on": true, "at": true, "by": true, "for": true, "with": true, "from": true,
+	"as": true, "that": true, "which": true, "who": true, "it": true, "its": true,
+	"is": true, "be": true, "are": true, "was": true, "not": true, "this": true,
+}
+
+// whether the word k is kept out of the graph by the stopword policy
+func (opts buildOptions) stopped(k string) bool {
+	return opts.stopPolicy != normalStop && opts.stopwords[k]
+}
+
+// whether any class of undefined word is dropped or remapped
+func (opts buildOptions) handlesUndef() bool {
+	return opts.numbers != keepUndef || opts.inflections != keepUndef ||
+		opts.typos != keepUndef || opts.leaves != keepUndef
+}
+
+// action for an undefined word of class
+func (opts buildOptions) undefAction(class string) undefAction {
+	switch class {
+	case undefNumber:
+		return opts.numbers
+	case undefInflection:
+		return opts.inflections
+	case undefTypo:
+		return opts.typos
+	}
+	return opts.leaves
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -13,13 +15,21 @@ type dictInterface interface {
 	setFolder(string) 
 	getNames() []string
 	getDef(string) string
+	defWords(string) []string
 	Print()
 	PrintSize()
 	loadData(string)
 	AddData(*Graph)
-	expandDef([]string, string) string
+	setKeyOptions(keyOptions)
+	getKeyOptions() keyOptions
+	setBuildOptions(buildOptions)
+	getBuildOptions() buildOptions
+	setExpandOptions(expandOptions)
+	expandDef([]string, string) (string, error)
+	expandTree([]string, string) (*expTree, error)
 	verify([]string) bool
-	export([]string) map[string][]string
+	export([]string) (map[string][]string, error)
+	exportStream([]string, io.Writer) error
 }
 
 type Dictionary struct {
@@ -27,11 +37,23 @@ type Dictionary struct {
 	//definitions []*Definition
 	// ^--- old DS
 	folder string
+
+	keyOpts  keyOptions
+	build    buildOptions
+	prepared bool
+	// inflected word -> headword it was merged into
+	merged map[string]string
+	// undefined word -> headword it was remapped to, "" if it was dropped
+	undef map[string]string
+
+	expOpts expandOptions
 }
 
 type Definition struct {
 	name  string
 	words []string
+	// graph keys of words, see prepare()
+	keys []string
 }
 
 func (d *Dictionary) setFolder(fp string) {
@@ -75,6 +97,16 @@ func (d *Dictionary) getDef(k string) string {
 	}
 }
 
+// graph keys of the words defining k
+func (d *Dictionary) defWords(k string) []string {
+	d.prepare()
+
+	if defn, ok := d.definitions[k]; ok {
+		return defn.keys
+	}
+	return nil
+}
+
 func (d *Dictionary) Print() {
 	for _, v := range d.definitions {
 		fmt.Println("name: ", v.name)
@@ -110,47 +142,207 @@ func (d *Dictionary) loadData(fn string) {
 }
 
 // Helper Function : loadData
+// names that normalise to the same key share one definition
 func (d *Dictionary) addDef(n string, w []string) {
+	n = d.keyOpts.normKey(n)
+	if n == "" {
+		return
+	}
+	w = d.keyOpts.normKeys(w)
+
+	d.prepared = false
+
+	if defn, ok := d.definitions[n]; ok && d.keyOpts != (keyOptions{}) {
+		defn.words = append(defn.words, w...)
+		return
+	}
+
 	defn := &Definition{name: n, words: w}
 	d.definitions[n] = defn
 }
 
+// the loaded definitions are keyed again, names that now normalise to the same key share
+// one definition, so keys can be normalised further but not back
+func (d *Dictionary) setKeyOptions(opts keyOptions) {
+	d.keyOpts = opts
+
+	defs := d.definitions
+	d.definitions = make(map[string]*Definition, len(defs))
+
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		d.addDef(name, defs[name].words)
+	}
+}
+
+func (d *Dictionary) getKeyOptions() keyOptions {
+	return d.keyOpts
+}
+
+func (d *Dictionary) setBuildOptions(opts buildOptions) {
+	d.build = opts
+	d.prepared = false
+}
+
+func (d *Dictionary) getBuildOptions() buildOptions {
+	return d.build
+}
+
+// works out the graph key of every definition word according to the build options
+// the graph and the expansions both use the keys, getDef still shows the words
+func (d *Dictionary) prepare() {
+	if d.prepared {
+		return
+	}
+	d.prepared = true
+
+	lem := newLemmatizer(d.definitions)
+
+	d.merged = make(map[string]string)
+	if d.build.lemmatize {
+		for k, lemma := range lem.stubs {
+			d.merged[k] = lemma
+		}
+	}
+
+	d.undef = make(map[string]string)
+	var cls *undefClassifier
+	if d.build.handlesUndef() {
+		cls = newUndefClassifier(lem.heads, d.wordCounts())
+	}
+
+	var phrases *phraseMatcher
+	if d.build.phrases {
+		phrases = newPhraseMatcher(d.getNames())
+	}
+
+	for _, v := range d.definitions {
+		words := v.words
+		if phrases != nil {
+			words = phrases.match(words)
+		}
+		v.keys = make([]string, 0, len(words))
+		for _, word := range words {
+			key := word
+			if d.build.lemmatize {
+				key = lem.lemma(word)
+				if key != word {
+					d.merged[word] = key
+				}
+			}
+			if cls != nil && !lem.heads[key] {
+				class, head := cls.classify(key)
+				switch d.build.undefAction(class) {
+				case dropUndef:
+					d.undef[key] = ""
+					continue
+				case remapUndef:
+					// the classifier doesn't know which headwords were merged away
+					if head != "" && d.build.lemmatize {
+						head = lem.lemma(head)
+					}
+					if head != "" {
+						d.undef[key] = head
+						key = head
+					}
+				}
+			}
+			v.keys = append(v.keys, key)
+		}
+	}
+}
+
+// Helper Function : prepare
+// how many times each word is used in definitions
+func (d *Dictionary) wordCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, v := range d.definitions {
+		for _, word := range v.words {
+			counts[word]++
+		}
+	}
+	return counts
+}
+
 // Transfers Data in Dictionary to Graph
 func (d *Dictionary) AddData(g *Graph) {
 	fmt.Println("adding data to graph...")
 
+	d.prepare()
+
 	for _, v := range d.definitions {
+		// inflection stubs are merged into their headword
+		if _, ok := d.merged[v.name]; ok {
+			continue
+		}
+		if d.build.stopped(v.name) {
+			g.stop(v.name)
+			continue
+		}
 		g.AddVertex(v.name)
-		for _, word := range v.words {
+		for _, word := range v.keys {
+			if d.build.stopped(word) {
+				g.stop(word)
+				continue
+			}
 			g.AddVertex(word)
 		}
 	}
 
 	for _, v := range d.definitions {
-		for _, word := range v.words {
+		if _, ok := d.merged[v.name]; ok || d.build.stopped(v.name) {
+			continue
+		}
+		for _, word := range v.keys {
 			// a defines b .. word defines name
-			if word != v.name {
+			if word != v.name && !d.build.stopped(word) {
 				g.AddEdge(word, v.name)
 			}
 		}
 	}
 
+	if d.build.stopPolicy != normalStop {
+		fmt.Println("stopwords kept out of graph: ", len(g.stopped))
+	}
+
 }
 
-// very slow implementation!
-// recursion is slowing down runtime!
-func (d *Dictionary) expandDef(delNodes []string, k string) string {
-	wordMap := make(map[string]bool)
-	var defn []string
+// writes what prepare() did to the words, lemmas.json and undefMapped.json
+// g is the graph built from d
+func (d *Dictionary) buildReport(g *Graph) {
+	d.prepare()
 
-	if k == "" {
-		return ""
+	if d.build.lemmatize {
+		fmt.Println("vertices merged: ", len(d.merged))
+		writeJSON(d.merged, d.folder+"lemmas.json")
 	}
 
+	if d.build.handlesUndef() {
+		fmt.Println("undefined words dropped or remapped: ", len(d.undef))
+		writeJSON(d.undef, d.folder+"undefMapped.json")
+	}
+}
+
+func (d *Dictionary) setExpandOptions(opts expandOptions) {
+	d.expOpts = opts
+}
+
+// builds the word lookup for delNodes once so it can be reused across expansions
+func (d *Dictionary) newExpander(delNodes []string) *expander {
+	d.prepare()
+
+	wordMap := make(map[string]bool)
+
+	// stopwords outside the graph are never expanded
 	for _, val := range d.definitions {
-		wordMap[val.name] = false
-		for _, word := range val.words {
-			wordMap[word] = false
+		wordMap[val.name] = d.build.stopped(val.name)
+		for _, word := range val.keys {
+			wordMap[word] = d.build.stopped(word)
 		}
 	}
 
@@ -158,113 +350,317 @@ func (d *Dictionary) expandDef(delNodes []string, k string) string {
 		wordMap[val] = true
 	}
 
-	defn = d.findDef(k)
+	return newExpander(d.expOpts, wordMap)
+}
+
+func (d *Dictionary) expandDef(delNodes []string, k string) (string, error) {
+	if k == "" {
+		return "", nil
+	}
+
+	return d.expandWith(d.newExpander(delNodes), k)
+}
+
+// expands k reusing the lookup and memoised expansions of e
+// fails naming the cycle if delNodes is not an FVS
+func (d *Dictionary) expandWith(e *expander, k string) (string, error) {
+	if k == "" {
+		return "", nil
+	}
+
+	if err := e.enter(k); err != nil {
+		return "", err
+	}
+	defer e.leave(k)
+
+	defn := d.findDef(k)
 
 	var newDefn []string = []string{}
 	for _, val := range defn {
 		// get rid of self loops
-		if k == val {
-			newDefn = append(newDefn, val)
-			continue
-		}
-		wmBool, ok := wordMap[val]
-		if !ok || wmBool {
+		wmBool, ok := e.wordMap[val]
+		if k == val || !ok || wmBool {
 			newDefn = append(newDefn, val)
-			continue
-		}
-		expand := d.recursiveSearch(wordMap, val)
-		if len(expand) != 0 {
-			newDefn = append(newDefn, expand...)
 		} else {
-			newDefn = append(newDefn, val)
+			expand, _, err := d.recursiveSearch(e, val, 1)
+			if err != nil {
+				return "", err
+			}
+			if len(expand) != 0 {
+				newDefn = append(newDefn, expand...)
+			} else {
+				newDefn = append(newDefn, val)
+			}
 		}
-	}
-
-	var str string
 
-	for i, val := range newDefn {
-		if i == 0 {
-			str = str + val
-		} else {
-			str = str + " " + val
+		var cut bool
+		if newDefn, cut = e.limit(newDefn); cut {
+			break
 		}
 	}
 
-	return str
+	return strings.Join(newDefn, " "), nil
 }
 
 // Helper Function : expandDef
-func (d *Dictionary) recursiveSearch(wordMap map[string]bool, k string) []string {
-	val, ok := wordMap[k]
+// every word is only expanded once, later lookups are served from e.memo
+// also returns whether the expansion was cut short by the depth limit
+func (d *Dictionary) recursiveSearch(e *expander, k string, depth int) ([]string, bool, error) {
+	val, ok := e.wordMap[k]
 	if !ok || val {
-		return []string{}
-	} else {
-		defn := d.findDef(k)
-		var newDefn []string = []string{}
-		for _, val := range defn {
-			// get rid of self loops
-			if k == val {
-				newDefn = append(newDefn, val)
-				continue
-			}
-			wmBool, ok := wordMap[val]
-			if !ok || wmBool {
-				newDefn = append(newDefn, val)
-				continue
-			}
+		return []string{}, false, nil
+	}
+
+	// free words have nothing to cut, like in subtree
+	defn := d.findDef(k)
+	if len(defn) == 0 {
+		return []string{}, false, nil
+	}
 
-			expand := d.recursiveSearch(wordMap, val)
+	if memo, ok := e.memo[k]; ok && e.fits(depth, e.heights[k]) {
+		return memo, false, nil
+	}
+	if memo, ok := e.memo[memoKey(k, depth, true)]; ok {
+		return memo, true, nil
+	}
+
+	if e.tooDeep(depth) {
+		return []string{k, truncMarker}, true, nil
+	}
+
+	if err := e.enter(k); err != nil {
+		return nil, false, err
+	}
+	defer e.leave(k)
+
+	var newDefn []string = []string{}
+	var cut bool
+	height := 0
+	for _, val := range defn {
+		// get rid of self loops
+		wmBool, ok := e.wordMap[val]
+		if k == val || !ok || wmBool {
+			newDefn = append(newDefn, val)
+		} else {
+			expand, subCut, err := d.recursiveSearch(e, val, depth+1)
+			if err != nil {
+				return nil, false, err
+			}
+			cut = cut || subCut
+			height = childHeight(e.heights, val, subCut, height)
 
 			if len(expand) != 0 {
 				newDefn = append(newDefn, expand...)
 			} else {
 				newDefn = append(newDefn, val)
 			}
+		}
 
+		var full bool
+		if newDefn, full = e.limit(newDefn); full {
+			break
 		}
-		return newDefn
 	}
+
+	e.memo[memoKey(k, depth, cut)] = newDefn
+	if !cut {
+		e.heights[k] = height
+	}
+
+	return newDefn, cut, nil
+}
+
+func (d *Dictionary) expandTree(delNodes []string, k string) (*expTree, error) {
+	if k == "" {
+		return nil, nil
+	}
+
+	return d.treeWith(d.newExpander(delNodes), k)
+}
+
+// tree version of expandWith, subtrees are shared between expansions of e
+func (d *Dictionary) treeWith(e *expander, k string) (*expTree, error) {
+	if err := e.enter(k); err != nil {
+		return nil, err
+	}
+	defer e.leave(k)
+
+	defn := d.findDef(k)
+
+	root := &expTree{Word: k, Base: e.wordMap[k], Free: len(defn) == 0}
+
+	for _, val := range defn {
+		child, _, err := d.subtree(e, k, val, 1)
+		if err != nil {
+			return nil, err
+		}
+		root.Children = append(root.Children, child)
+	}
+
+	return root, nil
+}
+
+// Helper Function : expandTree
+// also returns whether the subtree was cut short by the depth limit
+func (d *Dictionary) subtree(e *expander, parent string, k string, depth int) (*expTree, bool, error) {
+	// get rid of self loops
+	if k == parent {
+		return &expTree{Word: k}, false, nil
+	}
+
+	wmBool, ok := e.wordMap[k]
+	if !ok || wmBool {
+		return &expTree{Word: k, Base: wmBool}, false, nil
+	}
+
+	if memo, ok := e.trees[k]; ok && e.fits(depth, e.treeHeights[k]) {
+		return memo, false, nil
+	}
+	if memo, ok := e.trees[memoKey(k, depth, true)]; ok {
+		return memo, true, nil
+	}
+
+	defn := d.findDef(k)
+	if len(defn) == 0 {
+		return &expTree{Word: k, Free: true}, false, nil
+	}
+
+	if e.tooDeep(depth) {
+		return &expTree{Word: k, Truncated: true}, true, nil
+	}
+
+	if err := e.enter(k); err != nil {
+		return nil, false, err
+	}
+	defer e.leave(k)
+
+	node := &expTree{Word: k}
+	var cut bool
+	height := 0
+	for _, val := range defn {
+		child, subCut, err := d.subtree(e, k, val, depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+		cut = cut || subCut
+		height = childHeight(e.treeHeights, val, subCut, height)
+		node.Children = append(node.Children, child)
+	}
+
+	e.trees[memoKey(k, depth, cut)] = node
+	if !cut {
+		e.treeHeights[k] = height
+	}
+
+	return node, cut, nil
+}
+
+// Helper Function : record
+// the words expandWith gives for node, a tree of treeWith, memoised per node
+func (d *Dictionary) flatten(e *expander, node *expTree) []string {
+	if words, ok := e.flat[node]; ok {
+		return words
+	}
+
+	words := []string{}
+	for _, child := range node.Children {
+		if child.Truncated {
+			words = append(words, child.Word, truncMarker)
+		} else if len(child.Children) != 0 {
+			words = append(words, d.flatten(e, child)...)
+		} else {
+			words = append(words, child.Word)
+		}
+
+		var full bool
+		if words, full = e.limit(words); full {
+			break
+		}
+	}
+
+	e.flat[node] = words
+
+	return words
 }
 
 // Helper function : expandDef
 func (d *Dictionary) findDef(k string) []string {
 	defn, ok := d.definitions[k]
 	if ok {
-		return defn.words
+		return defn.keys
 	} else {
 		return []string{}
 	}
 }
 
-// very slow implementation!
-// implementation takes hours on my computer to run w/ current speed of expandDef!
 func (d *Dictionary) verify(delNodes []string) bool {
 
 	fmt.Println("verifying...")
 
-	for _, val := range d.definitions {
-		d.expandDef(delNodes, val.name)
+	names := d.getNames()
+
+	err := d.newExpander(delNodes).parallel(len(names), func(w *expander, i int) error {
+		_, err := d.expandWith(w, names[i])
+		return err
+	})
+	if err != nil {
+		fmt.Println(err)
+		return false
 	}
 
 	return true
 
 }
 
-// very slow implementation!
-// implementation takes hours on my computer to run w/ current speed of expandDef!
-func (d *Dictionary) export(delNodes []string) map[string][]string {
+func (d *Dictionary) export(delNodes []string) (map[string][]string, error) {
 	fmt.Println("exporting...")
 
+	names := d.getNames()
+	sols := make([][]string, len(names))
+
+	err := d.newExpander(delNodes).parallel(len(names), func(w *expander, i int) error {
+		expand, err := d.expandWith(w, names[i])
+		if err != nil {
+			return err
+		}
+		sols[i] = []string{d.getDef(names[i]), expand}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	var set map[string][]string = make(map[string][]string)
 
-	for _, val := range d.definitions {
-		var sol []string
-		sol = append(sol, d.getDef(val.name))
-		sol = append(sol, d.expandDef(delNodes, val.name))
-		set[val.name] = sol
+	for i, name := range names {
+		set[name] = sols[i]
+	}
+
+	return set, nil
+}
+
+// writes one solRecord per definition to out without holding the whole export in memory
+func (d *Dictionary) exportStream(delNodes []string, out io.Writer) error {
+	fmt.Println("exporting...")
+
+	return d.newExpander(delNodes).stream(d.getNames(), d.record, out)
+}
+
+// Helper Function : exportStream
+// k is only expanded once, as a tree, the text is read off it
+func (d *Dictionary) record(e *expander, k string) (solRecord, error) {
+	tree, err := d.treeWith(e, k)
+	if err != nil {
+		return solRecord{}, err
 	}
 
-	return set
+	return solRecord{
+		Word:     k,
+		Def:      d.getDef(k),
+		Expanded: strings.Join(d.flatten(e, tree), " "),
+		Depth:    e.treeDepth(tree),
+		Base:     baseWords(tree),
+	}, nil
 }
 
 type WNdict struct {
@@ -272,9 +668,26 @@ type WNdict struct {
 	definitions map[string][]*WNdef
 
 	folder string
+
+	expOpts expandOptions
+
+	// build the graph on synset IDs instead of lemma names
+	senses bool
+
+	// parts of speech kept in the graph, all of them if nil
+	pos map[string]bool
+
+	// phrase synsets like ice_cream.n.01 are kept and matched in glosses, see loadDB
+	// glosses are tokenised while loading, so it can't be a build option
+	phrases bool
+
+	keyOpts keyOptions
+	build   buildOptions
 }
 
 type WNdef struct {
+	ID         string
+	pos        string
 	name       string
 	origDef    string
 	regexDef   string
@@ -287,7 +700,41 @@ func (wn *WNdict) setFolder(fp string) {
 }
 
 func (wn *WNdict) getFolder() string {
-	return "data/wn/"
+	return wn.folder
+}
+
+// vertex key of the synset ID with lemma name, depending on whether senses are used
+func (wn *WNdict) key(ID string, name string) string {
+	if wn.senses {
+		return ID
+	}
+	return name
+}
+
+// splits a synset ID like dog.n.01 into its lemma, part of speech and sense number
+// lemmas can contain dots themselves so the ID is split from the right
+func splitID(ID string) (string, string, string) {
+	parts := strings.Split(ID, ".")
+	if len(parts) < 3 {
+		return ID, "", ""
+	}
+	n := len(parts)
+	return strings.Join(parts[:n-2], "."), parts[n-2], parts[n-1]
+}
+
+// rolls synset IDs up to their distinct lemma names
+func (wn *WNdict) lemmas(IDs []string) map[string][]string {
+	lemmas := make(map[string][]string)
+
+	for _, ID := range IDs {
+		name, _, _ := splitID(ID)
+		if def, ok := wn.IDMappings[ID]; ok {
+			name = def.name
+		}
+		lemmas[name] = append(lemmas[name], ID)
+	}
+
+	return lemmas
 }
 
 func (wn *WNdict) getNames() []string {
@@ -313,6 +760,32 @@ func (wn *WNdict) getDef(k string) string {
 	return str
 }
 
+// graph keys of the words defining k, synset IDs in sense mode
+func (wn *WNdict) defWords(k string) []string {
+	var words []string
+
+	if wn.senses {
+		if v, ok := wn.IDMappings[k]; ok {
+			for _, ID := range v.mappings {
+				if wn.allowed(ID) {
+					words = append(words, ID)
+				}
+			}
+		}
+		return words
+	}
+
+	for _, v := range wn.definitions[k] {
+		for i, word := range v.regexWords {
+			if wn.allowed(v.mappings[i]) {
+				words = append(words, word)
+			}
+		}
+	}
+
+	return words
+}
+
 func (wn *WNdict) Print() {
 	for _, v := range wn.definitions {
 		for _, d := range v {
@@ -359,7 +832,7 @@ func (wn *WNdict) loadData(fn string) {
 			mappings = append(mappings, word.(string))
 		}
 
-		def := &WNdef{name: name, origDef: origDef, regexDef: regexDef, regexWords: regexWords, mappings: mappings}
+		def := &WNdef{ID: ID, name: name, origDef: origDef, regexDef: regexDef, regexWords: regexWords, mappings: mappings}
 
 		wn.addDef(ID, def)
 	}
@@ -367,20 +840,157 @@ func (wn *WNdict) loadData(fn string) {
 
 // Helper Function : loadData
 func (wn *WNdict) addDef(ID string, def *WNdef) {
+	if def.pos == "" {
+		_, def.pos, _ = splitID(ID)
+	}
+	// regexWords line up with mappings and placeholders so none are dropped
+	def.name = wn.keyOpts.normKey(def.name)
+	for i, word := range def.regexWords {
+		if key := wn.keyOpts.normKey(word); key != "" {
+			def.regexWords[i] = key
+		}
+	}
 	wn.IDMappings[ID] = def
 	wn.definitions[def.name] = append(wn.definitions[def.name], def)
 }
 
+// like Dictionary.setKeyOptions, the synsets keep their IDs and order
+func (wn *WNdict) setKeyOptions(opts keyOptions) {
+	wn.keyOpts = opts
+
+	defs := wn.definitions
+	wn.definitions = make(map[string][]*WNdef, len(defs))
+
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, def := range defs[name] {
+			wn.addDef(def.ID, def)
+		}
+	}
+}
+
+func (wn *WNdict) getKeyOptions() keyOptions {
+	return wn.keyOpts
+}
+
+// WordNet words are lemmas already, so lemmatize has nothing to do here
+// its definition words are mapped onto synsets, so undefined words can't be dropped or
+// remapped and are always kept
+func (wn *WNdict) setBuildOptions(opts buildOptions) {
+	if opts.handlesUndef() {
+		fmt.Println("Error: WordNet keeps its undefined words, ignoring the undefined word actions")
+		opts.numbers, opts.inflections, opts.typos, opts.leaves = keepUndef, keepUndef, keepUndef, keepUndef
+	}
+	wn.build = opts
+}
+
+func (wn *WNdict) getBuildOptions() buildOptions {
+	return wn.build
+}
+
+// restricts the dictionary to synsets of the given parts of speech (n, v, a, s, r)
+// "a" also keeps satellite adjectives, words mapped to dropped synsets become leaves
+func (wn *WNdict) filterPOS(pos ...string) {
+	wn.pos = make(map[string]bool)
+	for _, p := range pos {
+		wn.pos[p] = true
+		if p == "a" {
+			wn.pos["s"] = true
+		}
+	}
+
+	for ID, def := range wn.IDMappings {
+		if !wn.pos[def.pos] {
+			delete(wn.IDMappings, ID)
+		}
+	}
+
+	for name, li := range wn.definitions {
+		var kept []*WNdef
+		for _, def := range li {
+			if wn.pos[def.pos] {
+				kept = append(kept, def)
+			}
+		}
+		if len(kept) == 0 {
+			delete(wn.definitions, name)
+		} else {
+			wn.definitions[name] = kept
+		}
+	}
+}
+
+// whether the synset ID is of a part of speech kept in the graph
+func (wn *WNdict) allowed(ID string) bool {
+	if wn.pos == nil {
+		return true
+	}
+	_, pos, _ := splitID(ID)
+	return wn.pos[pos]
+}
+
+// distinct parts of speech of the synsets of k
+func (wn *WNdict) getPOS(k string) []string {
+	var pos []string
+
+	seen := make(map[string]bool)
+	for _, def := range wn.findDefArr(k) {
+		if !seen[def.pos] {
+			seen[def.pos] = true
+			pos = append(pos, def.pos)
+		}
+	}
+
+	return pos
+}
+
+// counts the solution words by part of speech
+// lemmas count once towards every part of speech they have a synset in
+func (wn *WNdict) posCounts(delNodes []string) map[string]int {
+	counts := make(map[string]int)
+
+	for _, k := range delNodes {
+		if wn.senses {
+			_, pos, _ := splitID(k)
+			counts[pos]++
+			continue
+		}
+		for _, pos := range wn.getPOS(k) {
+			counts[pos]++
+		}
+	}
+
+	return counts
+}
+
 // Transfers Data in Dictionary to Graph
 func (wn *WNdict) AddData(g *Graph) {
 	fmt.Println("adding data to graph...")
 
+	if wn.senses {
+		wn.addSenses(g)
+		return
+	}
+
 	// add words
 	for _, li := range wn.definitions {
 		for _, v := range li {
+			if wn.build.stopped(v.name) {
+				g.stop(v.name)
+				continue
+			}
 			g.AddVertex(v.name)
-			for _, word := range v.regexWords {
-				g.AddVertex(word)
+			for i, word := range v.regexWords {
+				if wn.build.stopped(word) {
+					g.stop(word)
+				} else if wn.allowed(v.mappings[i]) {
+					g.AddVertex(word)
+				}
 			}
 		}
 	}
@@ -388,9 +998,12 @@ func (wn *WNdict) AddData(g *Graph) {
 	// add edges (has to happen once all words are in graph!)
 	for _, li := range wn.definitions {
 		for _, v := range li {
-			for _, word := range v.regexWords {
+			if wn.build.stopped(v.name) {
+				continue
+			}
+			for i, word := range v.regexWords {
 				// word defines name
-				if word != v.name {
+				if word != v.name && wn.allowed(v.mappings[i]) && !wn.build.stopped(word) {
 					g.AddEdge(word, v.name)
 				}
 			}
@@ -398,18 +1011,50 @@ func (wn *WNdict) AddData(g *Graph) {
 	}
 }
 
-// very slow implementation!
-func (wn *WNdict) expandDef(delNodes []string, k string) string {
-	wordMap := make(map[string]bool)
+// Helper Function : AddData
+// adds a vertex per synset with edges from the synsets its definition words were mapped to
+func (wn *WNdict) addSenses(g *Graph) {
+	for _, v := range wn.IDMappings {
+		if wn.build.stopped(v.name) {
+			g.stop(v.ID)
+			continue
+		}
+		g.AddVertex(v.ID)
+		for i, ID := range v.mappings {
+			if wn.build.stopped(v.regexWords[i]) {
+				g.stop(ID)
+			} else if wn.allowed(ID) {
+				g.AddVertex(ID)
+			}
+		}
+	}
 
-	if k == "" {
-		return ""
+	for _, v := range wn.IDMappings {
+		if wn.build.stopped(v.name) {
+			continue
+		}
+		for i, ID := range v.mappings {
+			// synset ID defines synset v.ID
+			if ID != v.ID && wn.allowed(ID) && !wn.build.stopped(v.regexWords[i]) {
+				g.AddEdge(ID, v.ID)
+			}
+		}
 	}
+}
+
+func (wn *WNdict) setExpandOptions(opts expandOptions) {
+	wn.expOpts = opts
+}
 
+// builds the word lookup for delNodes once so it can be reused across expansions
+func (wn *WNdict) newExpander(delNodes []string) *expander {
+	wordMap := make(map[string]bool)
+
+	// stopwords outside the graph are never expanded
 	for _, val := range wn.IDMappings {
-		wordMap[val.name] = false
-		for _, word := range val.regexWords {
-			wordMap[word] = false
+		wordMap[wn.key(val.ID, val.name)] = wn.build.stopped(val.name)
+		for i, word := range val.regexWords {
+			wordMap[wn.key(val.mappings[i], word)] = wn.build.stopped(word)
 		}
 	}
 
@@ -417,52 +1062,240 @@ func (wn *WNdict) expandDef(delNodes []string, k string) string {
 		wordMap[val] = true
 	}
 
+	return newExpander(wn.expOpts, wordMap)
+}
+
+func (wn *WNdict) expandDef(delNodes []string, k string) (string, error) {
+	if k == "" {
+		return "", nil
+	}
+
+	return wn.expandWith(wn.newExpander(delNodes), k)
+}
+
+// expands every synset of k reusing the lookup and memoised expansions of e
+// fails naming the cycle if delNodes is not an FVS
+func (wn *WNdict) expandWith(e *expander, k string) (string, error) {
+	if k == "" {
+		return "", nil
+	}
+
 	defnArr := wn.findDefArr(k)
 
 	var out string = ""
 
 	for idx, defn := range defnArr {
-		var str string = defn.regexDef
-		for i, val := range defn.regexWords {
-			if k == val {
-				str = strings.Replace(str, "%s", val, 1)
-				continue
-			}
-			expand := wn.recursiveSearch(wordMap, defn.mappings[i], val)
-			if len(expand) != 0 {
-				str = strings.Replace(str, "%s", expand, 1)
-			} else {
-				str = strings.Replace(str, "%s", val, 1)
-			}
+		if err := e.enter(defn.ID); err != nil {
+			return "", err
+		}
+		str, _, _, err := wn.expandSynset(e, defn, k, 1)
+		e.leave(defn.ID)
+		if err != nil {
+			return "", err
 		}
 		out = out + strconv.Itoa(idx+1) + ". " + str + "\n"
 	}
 
-	return out
+	return out, nil
 }
 
 // Helper Function : expandDef
-func (wn *WNdict) recursiveSearch(wordMap map[string]bool, ID string, k string) string {
-	val, ok := wordMap[k]
+// every synset is only expanded once, later lookups are served from e.glosses
+func (wn *WNdict) recursiveSearch(e *expander, ID string, k string, depth int) (string, bool, error) {
+	val, ok := e.wordMap[wn.key(ID, k)]
 	if !ok || val {
-		return ""
-	} else {
-		defn := wn.findDef(ID)
-		var str string = defn.regexDef
-		for i, val := range defn.regexWords {
-			if k == val {
-				str = strings.Replace(str, "%s", val, 1)
-				continue
-			}
-			expand := wn.recursiveSearch(wordMap, defn.mappings[i], val)
-			if len(expand) != 0 {
-				str = strings.Replace(str, "%s", expand, 1)
-			} else {
-				str = strings.Replace(str, "%s", val, 1)
-			}
+		return "", false, nil
+	}
+
+	// free synsets have nothing to cut, like in subtree
+	defn := wn.findDef(ID)
+	if defn.ID == "" {
+		return "", false, nil
+	}
+
+	if memo, ok := e.glosses[ID]; ok && e.fits(depth, e.heights[ID]) {
+		return memo, false, nil
+	}
+	if memo, ok := e.glosses[memoKey(ID, depth, true)]; ok {
+		return memo, true, nil
+	}
+
+	if e.tooDeep(depth) {
+		return k + " " + truncMarker, true, nil
+	}
+
+	if err := e.enter(ID); err != nil {
+		return "", false, err
+	}
+	defer e.leave(ID)
+
+	str, cut, height, err := wn.expandSynset(e, defn, k, depth+1)
+	if err != nil {
+		return "", false, err
+	}
+
+	e.glosses[memoKey(ID, depth, cut)] = str
+	if !cut {
+		e.heights[ID] = height
+	}
+
+	return str, cut, nil
+}
+
+// Helper Function : expandDef
+// fills the placeholders of one synset's regexDef with the expansions of its words
+// also returns whether the expansion was cut short by the depth limit and its height
+func (wn *WNdict) expandSynset(e *expander, defn *WNdef, k string, depth int) (string, bool, int, error) {
+	var str string = defn.regexDef
+	var cut bool
+	height := 0
+	for i, val := range defn.regexWords {
+		if k == val {
+			str = strings.Replace(str, "%s", val, 1)
+			continue
 		}
+		expand, subCut, err := wn.recursiveSearch(e, defn.mappings[i], val, depth)
+		if err != nil {
+			return "", false, 0, err
+		}
+		cut = cut || subCut
+		height = childHeight(e.heights, defn.mappings[i], subCut, height)
+		if len(expand) != 0 {
+			str = strings.Replace(str, "%s", expand, 1)
+		} else {
+			str = strings.Replace(str, "%s", val, 1)
+		}
+	}
+
+	str, _ = e.limitStr(str)
+
+	return str, cut, height, nil
+}
+
+func (wn *WNdict) expandTree(delNodes []string, k string) (*expTree, error) {
+	if k == "" {
+		return nil, nil
+	}
+
+	return wn.treeWith(wn.newExpander(delNodes), k)
+}
+
+// tree version of expandWith, the root has one child per synset of k
+func (wn *WNdict) treeWith(e *expander, k string) (*expTree, error) {
+	root := &expTree{Word: k, Base: e.wordMap[k]}
+
+	for _, defn := range wn.findDefArr(k) {
+		if err := e.enter(defn.ID); err != nil {
+			return nil, err
+		}
+		sense, _, _, err := wn.senseTree(e, defn, k, 1)
+		e.leave(defn.ID)
+		if err != nil {
+			return nil, err
+		}
+		root.Children = append(root.Children, sense)
+	}
+
+	root.Free = len(root.Children) == 0
+
+	return root, nil
+}
+
+// Helper Function : expandTree
+// also returns whether the subtree was cut short by the depth limit
+func (wn *WNdict) subtree(e *expander, ID string, k string, depth int) (*expTree, bool, error) {
+	wmBool, ok := e.wordMap[wn.key(ID, k)]
+	if !ok || wmBool {
+		return &expTree{Word: k, Sense: ID, Base: wmBool}, false, nil
+	}
+
+	if memo, ok := e.trees[ID]; ok && e.fits(depth, e.treeHeights[ID]) {
+		return memo, false, nil
+	}
+	if memo, ok := e.trees[memoKey(ID, depth, true)]; ok {
+		return memo, true, nil
+	}
+
+	defn := wn.findDef(ID)
+	if defn.ID == "" {
+		return &expTree{Word: k, Sense: ID, Free: true}, false, nil
+	}
+
+	if e.tooDeep(depth) {
+		return &expTree{Word: k, Sense: ID, Truncated: true}, true, nil
+	}
+
+	if err := e.enter(ID); err != nil {
+		return nil, false, err
+	}
+	defer e.leave(ID)
+
+	node, cut, height, err := wn.senseTree(e, defn, k, depth+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	e.trees[memoKey(ID, depth, cut)] = node
+	if !cut {
+		e.treeHeights[ID] = height
+	}
+
+	return node, cut, nil
+}
+
+// Helper Function : expandTree
+// builds the node of one synset with a child per mapped word of its definition
+// also returns its height, see expander.treeHeights
+func (wn *WNdict) senseTree(e *expander, defn *WNdef, k string, depth int) (*expTree, bool, int, error) {
+	node := &expTree{Word: k, Sense: defn.ID, Gloss: defn.origDef}
+	var cut bool
+	height := 0
+
+	for i, val := range defn.regexWords {
+		// get rid of self loops
+		if k == val {
+			node.Children = append(node.Children, &expTree{Word: val, Sense: defn.mappings[i]})
+			continue
+		}
+		child, subCut, err := wn.subtree(e, defn.mappings[i], val, depth)
+		if err != nil {
+			return nil, false, 0, err
+		}
+		cut = cut || subCut
+		height = childHeight(e.treeHeights, defn.mappings[i], subCut, height)
+		node.Children = append(node.Children, child)
+	}
+
+	return node, cut, height, nil
+}
+
+// Helper Function : record
+// the gloss expandSynset gives for node, a synset of treeWith, memoised per node
+// expanded synsets are the children with a gloss or children of their own
+func (wn *WNdict) flatten(e *expander, node *expTree) string {
+	if str, ok := e.flatGlosses[node]; ok {
 		return str
 	}
+
+	var str string = wn.findDef(node.Sense).regexDef
+	for _, child := range node.Children {
+		var expand string
+		if child.Truncated {
+			expand = child.Word + " " + truncMarker
+		} else if len(child.Children) != 0 || child.Gloss != "" {
+			expand = wn.flatten(e, child)
+		}
+		if len(expand) == 0 {
+			expand = child.Word
+		}
+		str = strings.Replace(str, "%s", expand, 1)
+	}
+
+	str, _ = e.limitStr(str)
+
+	e.flatGlosses[node] = str
+
+	return str
 }
 
 // Helper Function : expandDef
@@ -485,32 +1318,83 @@ func (wn *WNdict) findDefArr(k string) []*WNdef {
 	}
 }
 
-// very slow implementation!
 func (wn *WNdict) verify(delNodes []string) bool {
 
 	fmt.Println("verifying...")
 
-	for _, defnArr := range wn.definitions {
-		// expands all synsets anyway!
-		wn.expandDef(delNodes, defnArr[0].name)
+	// expands all synsets anyway!
+	names := wn.getNames()
+
+	err := wn.newExpander(delNodes).parallel(len(names), func(w *expander, i int) error {
+		_, err := wn.expandWith(w, names[i])
+		return err
+	})
+	if err != nil {
+		fmt.Println(err)
+		return false
 	}
 
 	return true
 
 }
 
-// very slow implementation!
-func (d *WNdict) export(delNodes []string) map[string][]string {
+func (d *WNdict) export(delNodes []string) (map[string][]string, error) {
 	fmt.Println("exporting...")
 
+	names := d.getNames()
+	sols := make([][]string, len(names))
+
+	err := d.newExpander(delNodes).parallel(len(names), func(w *expander, i int) error {
+		expand, err := d.expandWith(w, names[i])
+		if err != nil {
+			return err
+		}
+		sols[i] = []string{d.getDef(names[i]), expand, strings.Join(d.getPOS(names[i]), " ")}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	var set map[string][]string = make(map[string][]string)
 
-	for _, val := range d.definitions {
-		var sol []string
-		sol = append(sol, d.getDef(val[0].name))
-		sol = append(sol, d.expandDef(delNodes, val[0].name))
-		set[val[0].name] = sol
+	for i, name := range names {
+		set[name] = sols[i]
+	}
+
+	return set, nil
+}
+
+// writes one solRecord per word to out without holding the whole export in memory
+func (wn *WNdict) exportStream(delNodes []string, out io.Writer) error {
+	fmt.Println("exporting...")
+
+	return wn.newExpander(delNodes).stream(wn.getNames(), wn.record, out)
+}
+
+// Helper Function : exportStream
+// the depth is the deepest of the word's synsets, k is only expanded once, as a tree
+func (wn *WNdict) record(e *expander, k string) (solRecord, error) {
+	tree, err := wn.treeWith(e, k)
+	if err != nil {
+		return solRecord{}, err
+	}
+
+	var expand string = ""
+	depth := 0
+	for idx, sense := range tree.Children {
+		expand = expand + strconv.Itoa(idx+1) + ". " + wn.flatten(e, sense) + "\n"
+		if d := e.treeDepth(sense); d > depth {
+			depth = d
+		}
 	}
 
-	return set
+	return solRecord{
+		Word:     k,
+		POS:      wn.getPOS(k),
+		Def:      wn.getDef(k),
+		Expanded: expand,
+		Depth:    depth,
+		Base:     baseWords(tree),
+	}, nil
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// reads dictd dictionaries, a .index file of "headword<TAB>offset<TAB>length" lines
+// pointing into a .dict file (or its gzip compatible .dict.dz), numbers are in base 64
+
+const dictdDigits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// fn is the path without extension, like wrangle/dictd/gcide for gcide.index and gcide.dict.dz
+func LoadDictd(fn string) dictInterface {
+	start := time.Now()
+
+	fmt.Println("loading dictionary...")
+
+	dict := &Dictionary{definitions: make(map[string]*Definition)}
+
+	dict.setFolder("data/dictd/")
+
+	err := os.MkdirAll(dict.folder, 0755)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
+	err = dict.loadDictd(fn, defaultNormalizer())
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
+	dict.PrintSize()
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+
+	fmt.Println()
+
+	return dict
+}
+
+// headwords listed more than once have their definitions joined
+func (d *Dictionary) loadDictd(fn string, n *normalizer) error {
+	data, err := readDictdData(fn)
+	if err != nil {
+		return err
+	}
+
+	index, err := os.Open(fn + ".index")
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+
+	defs := make(map[string][]string)
+	var order []string
+
+	scanner := bufio.NewScanner(index)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 3 {
+			continue
+		}
+
+		// dictd keeps its metadata under headwords like 00-database-info
+		if strings.HasPrefix(fields[0], "00-database") || strings.HasPrefix(fields[0], "00database") {
+			continue
+		}
+
+		offset, err := dictdNumber(fields[1])
+		if err != nil {
+			return err
+		}
+		length, err := dictdNumber(fields[2])
+		if err != nil {
+			return err
+		}
+		if offset+length > int64(len(data)) {
+			return fmt.Errorf("%s: entry past the end of the data", fields[0])
+		}
+
+		name := phraseKey(n.clean(fields[0]))
+		if name == "" {
+			continue
+		}
+		if _, ok := defs[name]; !ok {
+			order = append(order, name)
+		}
+		defs[name] = append(defs[name], n.tokens(dictdBody(string(data[offset:offset+length]), fields[0]))...)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		d.addDef(name, defs[name])
+	}
+
+	return nil
+}
+
+// Helper Function : loadDictd
+// reads the uncompressed .dict or the compressed .dict.dz, whichever exists
+func readDictdData(fn string) ([]byte, error) {
+	if data, err := os.ReadFile(fn + ".dict"); err == nil {
+		return data, nil
+	}
+
+	file, err := os.Open(fn + ".dict.dz")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// Helper Function : loadDictd
+// entries usually start by repeating the headword, which isn't part of the definition
+func dictdBody(entry string, headword string) string {
+	first, rest, ok := strings.Cut(strings.TrimLeft(entry, "\n"), "\n")
+	if ok && strings.HasPrefix(strings.ToLower(strings.TrimSpace(first)), strings.ToLower(headword)) {
+		return rest
+	}
+	return entry
+}
+
+// Helper Function : loadDictd
+func dictdNumber(s string) (int64, error) {
+	var num int64
+	for _, r := range s {
+		digit := strings.IndexRune(dictdDigits, r)
+		if digit < 0 {
+			return 0, fmt.Errorf("bad dictd number %q", s)
+		}
+		num = num*64 + int64(digit)
+	}
+	return num, nil
+}
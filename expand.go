@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// appended where an expansion was cut short by the depth or token limit
+const truncMarker = "[...]"
+
+// limits applied while expanding definitions, 0 means unlimited
+type expandOptions struct {
+	maxDepth  int // levels of nested definitions expanded below the word itself
+	maxTokens int // longest expansion returned, in words
+	workers   int // goroutines used by verify and export, 0 means one per CPU
+}
+
+// state shared by every expansion against the same solution
+// wordMap is built once per solution, memo holds each word's expansion once computed
+type expander struct {
+	opts    expandOptions
+	wordMap map[string]bool
+	memo    map[string][]string
+	glosses map[string]string
+	trees   map[string]*expTree
+	depths  map[*expTree]int
+
+	// levels of nested definitions below each full (uncut) expansion in memo/glosses
+	// and trees, a full expansion can only be reused where it still fits the depth limit
+	heights     map[string]int
+	treeHeights map[string]int
+
+	// text of trees already turned into expansions, see record
+	flat        map[*expTree][]string
+	flatGlosses map[*expTree]string
+
+	// words currently being expanded, used to catch cycles
+	visiting map[string]bool
+	path     []string
+}
+
+func newExpander(opts expandOptions, wordMap map[string]bool) *expander {
+	return &expander{
+		opts:     opts,
+		wordMap:  wordMap,
+		memo:     make(map[string][]string),
+		glosses:  make(map[string]string),
+		trees:    make(map[string]*expTree),
+		depths:   make(map[*expTree]int),
+		visiting: make(map[string]bool),
+
+		heights:     make(map[string]int),
+		treeHeights: make(map[string]int),
+		flat:        make(map[*expTree][]string),
+		flatGlosses: make(map[*expTree]string),
+	}
+}
+
+// returns an expander sharing the read-only lookup of e with its own memos
+func (e *expander) fork() *expander {
+	return newExpander(e.opts, e.wordMap)
+}
+
+// most memoised expansions and trees a worker of stream keeps before starting over
+const streamMemo = 50000
+
+// number of memoised expansions and trees
+func (e *expander) memoSize() int {
+	return len(e.memo) + len(e.glosses) + len(e.trees) + len(e.flat) + len(e.flatGlosses)
+}
+
+// drops every memo, the lookup and the cycle check are kept
+func (e *expander) forget() {
+	fresh := newExpander(e.opts, e.wordMap)
+	fresh.visiting, fresh.path = e.visiting, e.path
+	*e = *fresh
+}
+
+// calls fn for 0 <= i < n on a pool of workers, each with an expander forked from e
+// stops handing out work after the first error, which is returned
+func (e *expander) parallel(n int, fn func(w *expander, i int) error) error {
+	workers := e.opts.workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	done := make(chan struct{})
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := e.fork()
+			for i := range jobs {
+				if err := fn(w, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(done)
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-done:
+			break feed
+		}
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	return firstErr
+}
+
+// marks k as being expanded, fails if k is already being expanded further up
+func (e *expander) enter(k string) error {
+	if e.visiting[k] {
+		var cycle []string
+		for i, v := range e.path {
+			if v == k {
+				cycle = append(cycle, e.path[i:]...)
+				break
+			}
+		}
+		cycle = append(cycle, k)
+		return fmt.Errorf("cycle in expansion, delNodes is not an FVS: %s", strings.Join(cycle, " -> "))
+	}
+
+	e.visiting[k] = true
+	e.path = append(e.path, k)
+
+	return nil
+}
+
+func (e *expander) leave(k string) {
+	delete(e.visiting, k)
+	e.path = e.path[:len(e.path)-1]
+}
+
+// whether expanding a word at depth goes past the depth limit
+func (e *expander) tooDeep(depth int) bool {
+	return e.opts.maxDepth > 0 && depth > e.opts.maxDepth
+}
+
+// whether a full expansion with height levels below it fits the depth limit at depth
+func (e *expander) fits(depth int, height int) bool {
+	return e.opts.maxDepth <= 0 || depth+height <= e.opts.maxDepth
+}
+
+// height of a parent given the full expansion of its child k, see heights
+func childHeight(heights map[string]int, k string, cut bool, height int) int {
+	if h, ok := heights[k]; ok && !cut && h+1 > height {
+		return h + 1
+	}
+	return height
+}
+
+// key for memoising an expansion, expansions cut by the depth limit depend on their depth
+func memoKey(k string, depth int, cut bool) string {
+	if !cut {
+		return k
+	}
+	return k + "@" + strconv.Itoa(depth)
+}
+
+// cuts words down to the token limit, returns whether anything was cut
+func (e *expander) limit(words []string) ([]string, bool) {
+	if e.opts.maxTokens > 0 && len(words) > e.opts.maxTokens {
+		return append(words[:e.opts.maxTokens:e.opts.maxTokens], truncMarker), true
+	}
+	return words, false
+}
+
+// string version of limit for WordNet glosses
+func (e *expander) limitStr(str string) (string, bool) {
+	if e.opts.maxTokens > 0 {
+		words := strings.Fields(str)
+		if len(words) > e.opts.maxTokens {
+			return strings.Join(words[:e.opts.maxTokens], " ") + " " + truncMarker, true
+		}
+	}
+	return str, false
+}
+
+// one node of a structured expansion, serialised as is for the UI
+// Children holds one node per word in the definition, in order
+// base words, free words and words cut by the depth limit are leaves
+type expTree struct {
+	Word      string     `json:"word"`
+	Sense     string     `json:"sense,omitempty"`
+	Gloss     string     `json:"gloss,omitempty"`
+	Base      bool       `json:"base,omitempty"`
+	Free      bool       `json:"free,omitempty"`
+	Truncated bool       `json:"truncated,omitempty"`
+	Children  []*expTree `json:"children,omitempty"`
+}
+
+// one line of a streamed solution export
+type solRecord struct {
+	Word     string   `json:"word"`
+	POS      []string `json:"pos,omitempty"`
+	Def      string   `json:"definition"`
+	Expanded string   `json:"expanded"`
+	Depth    int      `json:"depth"`
+	Base     []string `json:"base"`
+}
+
+// expands every name on the worker pool of e and writes one JSON object per line to out
+// records are written as soon as they are done, in no particular order
+// workers forget their memos past streamMemo entries so a large export doesn't keep them all
+func (e *expander) stream(names []string, record func(w *expander, k string) (solRecord, error), out io.Writer) error {
+	recs := make(chan solRecord, 64)
+	written := make(chan error)
+
+	go func() {
+		enc := json.NewEncoder(out)
+		var err error
+		for rec := range recs {
+			if err == nil {
+				err = enc.Encode(rec)
+			}
+		}
+		written <- err
+	}()
+
+	err := e.parallel(len(names), func(w *expander, i int) error {
+		if w.memoSize() > streamMemo {
+			w.forget()
+		}
+		rec, err := record(w, names[i])
+		if err != nil {
+			return err
+		}
+		recs <- rec
+		return nil
+	})
+	close(recs)
+
+	if werr := <-written; err == nil {
+		err = werr
+	}
+
+	return err
+}
+
+// levels of nested definitions below node, memoised per node as subtrees are shared
+func (e *expander) treeDepth(node *expTree) int {
+	if d, ok := e.depths[node]; ok {
+		return d
+	}
+
+	depth := 0
+	for _, child := range node.Children {
+		if len(child.Children) != 0 {
+			if d := e.treeDepth(child) + 1; d > depth {
+				depth = d
+			}
+		}
+	}
+
+	e.depths[node] = depth
+
+	return depth
+}
+
+// distinct base words among the leaves of node
+func baseWords(node *expTree) []string {
+	var words []string
+
+	seen := make(map[*expTree]bool)
+	found := make(map[string]bool)
+	set := []*expTree{node}
+
+	for len(set) != 0 {
+		n := set[len(set)-1]
+		set = set[:len(set)-1]
+
+		for _, child := range n.Children {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			if child.Base && !found[child.Word] {
+				found[child.Word] = true
+				words = append(words, child.Word)
+			}
+			set = append(set, child)
+		}
+	}
+
+	sort.Strings(words)
+
+	return words
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// t reaches x at depth 1, t2 only at depth 3, beyond the limit
+func depthDict() *Dictionary {
+	d := &Dictionary{definitions: make(map[string]*Definition)}
+	d.addDef("t", []string{"x"})
+	d.addDef("t2", []string{"y"})
+	d.addDef("y", []string{"z"})
+	d.addDef("z", []string{"x"})
+	d.addDef("x", []string{"w"})
+	d.setExpandOptions(expandOptions{maxDepth: 2})
+	return d
+}
+
+// expansions must not depend on what the expander memoised before
+func TestExpandMemoDepth(t *testing.T) {
+	d := depthDict()
+
+	want, err := d.expandWith(d.newExpander(nil), "t2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := d.newExpander(nil)
+	if _, err := d.expandWith(e, "t"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.expandWith(e, "t2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != want {
+		t.Errorf("t2 after t = %q, fresh = %q", got, want)
+	}
+	if want != "x "+truncMarker {
+		t.Errorf("t2 = %q, want %q", want, "x "+truncMarker)
+	}
+}
+
+func TestTreeMemoDepth(t *testing.T) {
+	d := depthDict()
+
+	fresh, err := d.treeWith(d.newExpander(nil), "t2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := d.newExpander(nil)
+	if _, err := d.treeWith(e, "t"); err != nil {
+		t.Fatal(err)
+	}
+	after, err := d.treeWith(e, "t2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := json.Marshal(fresh)
+	got, _ := json.Marshal(after)
+	if string(got) != string(want) {
+		t.Errorf("t2 after t = %s, fresh = %s", got, want)
+	}
+}
@@ -3,6 +3,6 @@ module noeldev.site/dictionary
 go 1.19
 
 require (
-	github.com/gorilla/mux v1.8.0 // indirect
-	golang.org/x/exp v0.0.0-20220722155223-a9213eeb770e // indirect
+	github.com/gorilla/mux v1.8.0
+	golang.org/x/text v0.13.0
 )
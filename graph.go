@@ -2,15 +2,20 @@ package main
 
 import (
 	"container/heap"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
+	"sort"
+	"strings"
 )
 
 type Graph struct {
 	vertices map[string]*Vertex
 	pq       PriorityQueue
 	pqMap    map[string]*Item
+	// words kept out of the graph by the stopword policy
+	stopped map[string]bool
 }
 
 type Vertex struct {
@@ -140,6 +145,14 @@ func (g *Graph) AddVertex(k string) {
 	}
 }
 
+// records that k was kept out of the graph instead of being added
+func (g *Graph) stop(k string) {
+	if g.stopped == nil {
+		g.stopped = make(map[string]bool)
+	}
+	g.stopped[k] = true
+}
+
 // function which returns whether the vertex with key k is in the graph
 func (g *Graph) containsVertex(k string) bool {
 	_, ok := g.vertices[k]
@@ -599,3 +612,454 @@ func (g *Graph) simAnneal(initial []string, listFree []string) []string {
 	}
 
 }
+
+/* Closure Functions */
+
+// result of a closure query, see closure()
+type closureResult struct {
+	Defined   []string   `json:"defined"`
+	Undefined []string   `json:"undefined"`
+	Cycles    [][]string `json:"cycles"`
+}
+
+// computes which words become definable from the known words, the words that stay
+// undefinable and the cycles (strongly connected components) blocking them
+// free words have no definition and are treated as known, the same as in verify()
+func (g *Graph) closure(known []string) closureResult {
+	fmt.Println("computing closure...")
+
+	knownSet := make(map[string]bool)
+	for _, k := range known {
+		knownSet[k] = true
+	}
+
+	order, _, rest := g.forward(knownSet)
+
+	var res closureResult
+
+	for _, k := range order {
+		if !knownSet[k] && modLen(g.vertices[k].inList) != 0 {
+			res.Defined = append(res.Defined, k)
+		}
+	}
+
+	for k := range rest {
+		res.Undefined = append(res.Undefined, k)
+	}
+	sort.Strings(res.Undefined)
+
+	res.Cycles = g.scc(rest)
+
+	return res
+}
+
+// propagates definability forward from the known words along the defines-edges
+// a word becomes definable once every word in its definition is known or definable
+// returns the reached words in topological order, their definitional depth
+// (0 for known and free words) and the set of words that were never reached
+func (g *Graph) forward(known map[string]bool) ([]string, map[string]int, map[string]bool) {
+	depth := make(map[string]int)
+	waiting := make(map[string]int)
+	rest := make(map[string]bool)
+
+	var queue []string
+
+	for _, k := range g.sortedKeys() {
+		n := modLen(g.vertices[k].inList)
+		if known[k] || n == 0 {
+			depth[k] = 0
+			queue = append(queue, k)
+		} else {
+			waiting[k] = n
+			rest[k] = true
+		}
+	}
+
+	for i := 0; i < len(queue); i++ {
+		vert := g.vertices[queue[i]]
+		for _, out := range vert.outList {
+			if !rest[out.key] {
+				continue
+			}
+			if depth[vert.key]+1 > depth[out.key] {
+				depth[out.key] = depth[vert.key] + 1
+			}
+			waiting[out.key]--
+			if waiting[out.key] == 0 {
+				delete(rest, out.key)
+				queue = append(queue, out.key)
+			}
+		}
+	}
+
+	return queue, depth, rest
+}
+
+// returns the keys of all vertices in sorted order
+func (g *Graph) sortedKeys() []string {
+	keys := make([]string, 0, len(g.vertices))
+	for k := range g.vertices {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// finds the cyclic strongly connected components of the subgraph induced by keep
+// (all vertices if keep is nil) using an iterative version of Tarjan's algorithm
+func (g *Graph) scc(keep map[string]bool) [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+
+	var stack []string
+	var comps [][]string
+
+	inSub := func(k string) bool {
+		if k == "" {
+			return false
+		}
+		if keep == nil {
+			return true
+		}
+		return keep[k]
+	}
+
+	type frame struct {
+		vert *Vertex
+		i    int
+	}
+
+	for _, root := range g.sortedKeys() {
+		if _, seen := index[root]; seen || !inSub(root) {
+			continue
+		}
+
+		index[root] = len(index)
+		low[root] = index[root]
+		stack = append(stack, root)
+		onStack[root] = true
+
+		work := []frame{{g.vertices[root], 0}}
+
+		for len(work) != 0 {
+			f := &work[len(work)-1]
+			key := f.vert.key
+
+			if f.i < len(f.vert.outList) {
+				next := f.vert.outList[f.i]
+				f.i++
+
+				if !inSub(next.key) {
+					continue
+				}
+				if _, seen := index[next.key]; !seen {
+					index[next.key] = len(index)
+					low[next.key] = index[next.key]
+					stack = append(stack, next.key)
+					onStack[next.key] = true
+					work = append(work, frame{next, 0})
+				} else if onStack[next.key] && index[next.key] < low[key] {
+					low[key] = index[next.key]
+				}
+				continue
+			}
+
+			vert := f.vert
+			work = work[:len(work)-1]
+			if len(work) != 0 {
+				parent := work[len(work)-1].vert.key
+				if low[key] < low[parent] {
+					low[parent] = low[key]
+				}
+			}
+
+			if low[key] == index[key] {
+				var comp []string
+				for {
+					top := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					delete(onStack, top)
+					comp = append(comp, top)
+					if top == key {
+						break
+					}
+				}
+				if len(comp) > 1 || containsEdge(vert, key) {
+					sort.Strings(comp)
+					comps = append(comps, comp)
+				}
+			}
+		}
+	}
+
+	return comps
+}
+
+/* Target Functions */
+
+// finds a small set of extra words that makes target definable from the known words
+// the FVS is only solved on the ancestors of target, which are exactly the words
+// that have to be expanded to define it, and the result is culled afterwards
+// also returns the expansion tree of target cut at the known and extra words
+func (g *Graph) targetFVS(target string, known []string) ([]string, expGraph) {
+	fmt.Println("searching for target FVS...")
+
+	knownSet := make(map[string]bool)
+	for _, k := range known {
+		knownSet[k] = true
+	}
+
+	anc := g.ancestors(target, knownSet)
+
+	sub := g.subgraph(anc)
+	sub.pqInit()
+	extra := sub.FVS()
+
+	// try dropping the target first so it is only learned directly if it has to be
+	for i, k := range extra {
+		if k == target {
+			extra[0], extra[i] = extra[i], extra[0]
+			break
+		}
+	}
+
+	sub = g.subgraph(anc)
+	extra = sub.cullSol(extra, sub.top())
+
+	base := make(map[string]bool)
+	for k := range knownSet {
+		base[k] = true
+	}
+	for _, k := range extra {
+		base[k] = true
+	}
+
+	return extra, g.expansionGraph(target, base)
+}
+
+// returns target and every word it transitively depends on, not searching past known words
+func (g *Graph) ancestors(target string, known map[string]bool) map[string]bool {
+	anc := make(map[string]bool)
+
+	if !g.containsVertex(target) || known[target] {
+		return anc
+	}
+
+	anc[target] = true
+	set := []string{target}
+
+	for len(set) != 0 {
+		key := set[len(set)-1]
+		set = set[:len(set)-1]
+
+		for _, v := range g.vertices[key].inList {
+			if v.key == "" || known[v.key] || anc[v.key] {
+				continue
+			}
+			anc[v.key] = true
+			set = append(set, v.key)
+		}
+	}
+
+	return anc
+}
+
+// returns a fresh copy of the subgraph induced by the vertices in keys
+func (g *Graph) subgraph(keys map[string]bool) *Graph {
+	sub := &Graph{vertices: make(map[string]*Vertex), pqMap: make(map[string]*Item)}
+
+	for k := range keys {
+		if g.containsVertex(k) {
+			sub.AddVertex(k)
+		}
+	}
+
+	for k := range keys {
+		vert := g.getVertex(k)
+		if vert == nil {
+			continue
+		}
+		for _, out := range vert.outList {
+			if keys[out.key] {
+				sub.AddEdge(k, out.key)
+			}
+		}
+	}
+
+	return sub
+}
+
+// builds the tree of words expanded when defining k, base words are leaves
+func (g *Graph) expansionGraph(k string, base map[string]bool) expGraph {
+	var eg expGraph
+
+	if !g.containsVertex(k) {
+		return eg
+	}
+
+	seen := map[string]bool{k: true}
+	set := []string{k}
+
+	for len(set) != 0 {
+		key := set[0]
+		set = set[1:]
+
+		eg.Nodes = append(eg.Nodes, node{key})
+
+		if base[key] && key != k {
+			continue
+		}
+
+		for _, neighbor := range g.vertices[key].inList {
+			if neighbor.key == "" {
+				continue
+			}
+
+			eg.Links = append(eg.Links, link{key, neighbor.key})
+
+			if !seen[neighbor.key] {
+				seen[neighbor.key] = true
+				set = append(set, neighbor.key)
+			}
+		}
+	}
+
+	return eg
+}
+
+/* Curriculum Functions */
+
+// learning order of the words left once the solution words are known, see curriculum()
+type curriculum struct {
+	Base   []string   `json:"base"`
+	Free   []string   `json:"free"`
+	Order  []string   `json:"order"`
+	Levels [][]string `json:"levels"`
+}
+
+// arranges every word outside of delNodes so it comes after all words in its definition
+// Levels[i] holds the words of definitional depth i+1, Order is the levels concatenated
+// fails if delNodes is not an FVS, as the words left in cycles can't be ordered
+func (g *Graph) curriculum(delNodes []string) (curriculum, error) {
+	fmt.Println("ordering curriculum...")
+
+	known := make(map[string]bool)
+	for _, k := range delNodes {
+		known[k] = true
+	}
+
+	order, depth, rest := g.forward(known)
+
+	var c curriculum
+
+	if len(rest) != 0 {
+		cycles := g.scc(rest)
+		if len(cycles) != 0 {
+			return c, errors.New("not an FVS, words left in a cycle: " + strings.Join(cycles[0], ", "))
+		}
+	}
+
+	for _, k := range order {
+		d := depth[k]
+		if d == 0 {
+			if known[k] {
+				c.Base = append(c.Base, k)
+			} else {
+				c.Free = append(c.Free, k)
+			}
+			continue
+		}
+		for len(c.Levels) < d {
+			c.Levels = append(c.Levels, []string{})
+		}
+		c.Levels[d-1] = append(c.Levels[d-1], k)
+	}
+
+	for _, level := range c.Levels {
+		sort.Strings(level)
+		c.Order = append(c.Order, level...)
+	}
+
+	return c, nil
+}
+
+/* Glossary Functions */
+
+// result of solving glossary terms against a base vocabulary, see glossary()
+type glossaryResult struct {
+	// terms that have to be taught directly
+	Teach []string `json:"teach"`
+	// terms definable from the base vocabulary and the taught terms, in the order they can be defined
+	Defined []string `json:"defined"`
+	// terms that are already part of the base vocabulary
+	Known []string `json:"known"`
+	// words used in definitions that are neither known nor terms
+	Gaps []string `json:"gaps"`
+}
+
+// computes the FVS of the glossary terms only, treating the known words as defined
+// like targetFVS the search is limited to the words the terms depend on
+// gaps have no definition, so like free words they don't need solving but are reported
+func (g *Graph) glossary(terms []string, known []string) glossaryResult {
+	fmt.Println("solving glossary...")
+
+	knownSet := make(map[string]bool)
+	for _, k := range known {
+		knownSet[k] = true
+	}
+
+	termSet := make(map[string]bool)
+	for _, k := range terms {
+		termSet[k] = true
+	}
+
+	res := glossaryResult{Teach: []string{}, Defined: []string{}, Known: []string{}, Gaps: []string{}}
+
+	anc := make(map[string]bool)
+	for _, k := range terms {
+		if knownSet[k] {
+			if g.containsVertex(k) {
+				res.Known = append(res.Known, k)
+			}
+			continue
+		}
+		for a := range g.ancestors(k, knownSet) {
+			anc[a] = true
+		}
+	}
+
+	for k := range anc {
+		if !termSet[k] && modLen(g.vertices[k].inList) == 0 {
+			res.Gaps = append(res.Gaps, k)
+		}
+	}
+
+	sub := g.subgraph(anc)
+	sub.pqInit()
+	teach := sub.FVS()
+
+	sub = g.subgraph(anc)
+	res.Teach = append(res.Teach, sub.cullSol(teach, sub.top())...)
+
+	base := make(map[string]bool)
+	for k := range knownSet {
+		base[k] = true
+	}
+	for _, k := range res.Teach {
+		base[k] = true
+	}
+
+	order, _, _ := g.subgraph(anc).forward(base)
+	for _, k := range order {
+		if termSet[k] && !base[k] {
+			res.Defined = append(res.Defined, k)
+		}
+	}
+
+	sort.Strings(res.Teach)
+	sort.Strings(res.Known)
+	sort.Strings(res.Gaps)
+
+	return res
+}
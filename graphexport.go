@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writes the definition graph as GraphML (yEd), GEXF (Gephi) or DOT (Graphviz) with
+// the attributes below on every vertex
+
+// attributes of a vertex for the graph exports
+type vertAttrs struct {
+	in       int
+	out      int
+	free     bool // no definition, see top()
+	solution bool // part of delNodes
+	scc      int  // id of the cycle (strongly connected component) it is in, -1 if none
+	depth    int  // definitional depth from the solution, -1 if it can't be defined
+}
+
+// names and types of the attributes, in the order they are written
+var vertAttrNames = []struct {
+	name string
+	typ  string
+}{
+	{"in_degree", "int"},
+	{"out_degree", "int"},
+	{"free", "boolean"},
+	{"solution", "boolean"},
+	{"scc", "int"},
+	{"depth", "int"},
+}
+
+func (a vertAttrs) values() []string {
+	return []string{
+		fmt.Sprint(a.in), fmt.Sprint(a.out), fmt.Sprint(a.free),
+		fmt.Sprint(a.solution), fmt.Sprint(a.scc), fmt.Sprint(a.depth),
+	}
+}
+
+// works out the attributes of every vertex given the solution delNodes
+// depth is measured from delNodes and the free words like in forward()
+func (g *Graph) vertAttrs(delNodes []string) map[string]vertAttrs {
+	known := make(map[string]bool)
+	for _, k := range delNodes {
+		known[k] = true
+	}
+
+	_, depth, rest := g.forward(known)
+
+	sccs := make(map[string]int)
+	for i, comp := range g.scc(nil) {
+		for _, k := range comp {
+			sccs[k] = i
+		}
+	}
+
+	attrs := make(map[string]vertAttrs, len(g.vertices))
+
+	for k, v := range g.vertices {
+		a := vertAttrs{in: modLen(v.inList), out: modLen(v.outList), solution: known[k], scc: -1, depth: depth[k]}
+		a.free = a.in == 0
+		if i, ok := sccs[k]; ok {
+			a.scc = i
+		}
+		if rest[k] {
+			a.depth = -1
+		}
+		attrs[k] = a
+	}
+
+	return attrs
+}
+
+// Helper Function : writeGraphML, writeGEXF, writeDOT
+// calls edge for every edge between the sorted vertex indices
+func (g *Graph) eachEdge(keys []string, edge func(from int, to int) error) error {
+	index := make(map[string]int, len(keys))
+	for i, k := range keys {
+		index[k] = i
+	}
+
+	for i, k := range keys {
+		for _, out := range g.vertices[k].outList {
+			if out.key == "" {
+				continue
+			}
+			if err := edge(i, index[out.key]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (g *Graph) writeGraphML(out io.Writer, attrs map[string]vertAttrs) error {
+	w := bufio.NewWriter(out)
+	keys := g.sortedKeys()
+
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(w, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(w, `  <key id="label" for="node" attr.name="label" attr.type="string"/>`)
+	for _, a := range vertAttrNames {
+		fmt.Fprintf(w, "  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=\"%s\"/>\n", a.name, a.name, a.typ)
+	}
+	fmt.Fprintln(w, `  <graph id="dictionary" edgedefault="directed">`)
+
+	for i, k := range keys {
+		fmt.Fprintf(w, "    <node id=\"n%d\">\n      <data key=\"label\">%s</data>\n", i, xmlEscape(k))
+		for j, val := range attrs[k].values() {
+			fmt.Fprintf(w, "      <data key=\"%s\">%s</data>\n", vertAttrNames[j].name, val)
+		}
+		fmt.Fprintln(w, "    </node>")
+	}
+
+	err := g.eachEdge(keys, func(from int, to int) error {
+		_, err := fmt.Fprintf(w, "    <edge source=\"n%d\" target=\"n%d\"/>\n", from, to)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "  </graph>")
+	fmt.Fprintln(w, "</graphml>")
+
+	return w.Flush()
+}
+
+func (g *Graph) writeGEXF(out io.Writer, attrs map[string]vertAttrs) error {
+	w := bufio.NewWriter(out)
+	keys := g.sortedKeys()
+
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(w, `<gexf xmlns="http://gexf.net/1.3" version="1.3">`)
+	fmt.Fprintln(w, `  <graph mode="static" defaultedgetype="directed">`)
+	fmt.Fprintln(w, `    <attributes class="node">`)
+	for i, a := range vertAttrNames {
+		typ := a.typ
+		if typ == "int" {
+			typ = "integer"
+		}
+		fmt.Fprintf(w, "      <attribute id=\"%d\" title=\"%s\" type=\"%s\"/>\n", i, a.name, typ)
+	}
+	fmt.Fprintln(w, `    </attributes>`)
+
+	fmt.Fprintln(w, "    <nodes>")
+	for i, k := range keys {
+		fmt.Fprintf(w, "      <node id=\"%d\" label=\"%s\">\n        <attvalues>\n", i, xmlEscape(k))
+		for j, val := range attrs[k].values() {
+			fmt.Fprintf(w, "          <attvalue for=\"%d\" value=\"%s\"/>\n", j, val)
+		}
+		fmt.Fprintln(w, "        </attvalues>\n      </node>")
+	}
+	fmt.Fprintln(w, "    </nodes>")
+
+	fmt.Fprintln(w, "    <edges>")
+	id := 0
+	err := g.eachEdge(keys, func(from int, to int) error {
+		_, err := fmt.Fprintf(w, "      <edge id=\"%d\" source=\"%d\" target=\"%d\"/>\n", id, from, to)
+		id++
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "    </edges>")
+
+	fmt.Fprintln(w, "  </graph>")
+	fmt.Fprintln(w, "</gexf>")
+
+	return w.Flush()
+}
+
+// solution words are drawn as boxes, the other attributes are only carried along
+func (g *Graph) writeDOT(out io.Writer, attrs map[string]vertAttrs) error {
+	w := bufio.NewWriter(out)
+	keys := g.sortedKeys()
+
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	fmt.Fprintln(w, "digraph dictionary {")
+
+	for i, k := range keys {
+		fmt.Fprintf(w, "  n%d [label=\"%s\"", i, quote.Replace(k))
+		for j, val := range attrs[k].values() {
+			fmt.Fprintf(w, ", %s=%s", vertAttrNames[j].name, val)
+		}
+		if attrs[k].solution {
+			fmt.Fprint(w, ", shape=box")
+		}
+		fmt.Fprintln(w, "];")
+	}
+
+	err := g.eachEdge(keys, func(from int, to int) error {
+		_, err := fmt.Fprintf(w, "  n%d -> n%d;\n", from, to)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "}")
+
+	return w.Flush()
+}
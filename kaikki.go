@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// reads Wiktionary extracts from kaikki.org, one JSON entry per line like
+// {"word": "dog", "pos": "noun", "lang_code": "en", "senses": [{"glosses": ["..."]}]}
+
+// which entries are kept and how their senses become definitions
+type kaikkiOptions struct {
+	// dog is defined by its first sense only, later senses become dog#2, dog#3, ...
+	senses bool
+	// parts of speech to keep like noun, verb or adj, all if empty
+	pos map[string]bool
+	// language codes to keep like en, all if empty
+	langs map[string]bool
+}
+
+type kaikkiEntry struct {
+	Word     string `json:"word"`
+	POS      string `json:"pos"`
+	LangCode string `json:"lang_code"`
+	Senses   []struct {
+		Glosses []string `json:"glosses"`
+	} `json:"senses"`
+}
+
+func LoadKaikki(fn string, opts kaikkiOptions) dictInterface {
+	start := time.Now()
+
+	fmt.Println("loading dictionary...")
+
+	dict := &Dictionary{definitions: make(map[string]*Definition)}
+
+	dict.setFolder("data/kaikki/")
+
+	err := os.MkdirAll(dict.folder, 0755)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
+	err = dict.loadKaikki(fn, opts, defaultNormalizer())
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
+	dict.PrintSize()
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+
+	fmt.Println()
+
+	return dict
+}
+
+// entries of the same word (one per part of speech) are joined in file order
+func (d *Dictionary) loadKaikki(fn string, opts kaikkiOptions, n *normalizer) error {
+	file, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// word -> words of each sense
+	senses := make(map[string][][]string)
+	var order []string
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		var entry kaikkiEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("%s line %d: %w", fn, line, err)
+		}
+
+		if len(opts.pos) != 0 && !opts.pos[entry.POS] {
+			continue
+		}
+		if len(opts.langs) != 0 && !opts.langs[entry.LangCode] {
+			continue
+		}
+
+		name := phraseKey(n.clean(entry.Word))
+		if name == "" {
+			continue
+		}
+
+		for _, sense := range entry.Senses {
+			// subsenses repeat the glosses of their parents first
+			if len(sense.Glosses) == 0 {
+				continue
+			}
+			words := n.tokens(sense.Glosses[len(sense.Glosses)-1])
+			if len(words) == 0 {
+				continue
+			}
+			if _, ok := senses[name]; !ok {
+				order = append(order, name)
+			}
+			senses[name] = append(senses[name], words)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		if !opts.senses {
+			var words []string
+			for _, sense := range senses[name] {
+				words = append(words, sense...)
+			}
+			d.addDef(name, words)
+			continue
+		}
+
+		for i, sense := range senses[name] {
+			if i == 0 {
+				d.addDef(name, sense)
+			} else {
+				d.addDef(name+"#"+strconv.Itoa(i+1), sense)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// how words are turned into keys, applied the same way by the loaders, the graph,
+// solution files and the server so "God" and "god" can be made the same word
+// the zero value leaves words untouched, see setKeyOptions
+type keyOptions struct {
+	fold        bool // case folding
+	nfc         bool // unicode NFC, so composed and decomposed letters match
+	diacritics  bool // strip diacritics, café -> cafe
+	apostrophes bool // curly quotes become ' and surrounding quotes are trimmed
+	hyphens     bool // dashes are dropped like wrangle.py does, well-known -> wellknown
+}
+
+// quote marks treated as apostrophes
+var apostropheReplacer = strings.NewReplacer("’", "'", "‘", "'", "`", "'", "´", "'")
+
+// dash marks treated as hyphens
+var hyphenReplacer = strings.NewReplacer("-", "", "‐", "", "‑", "", "‒", "", "–", "", "—", "")
+
+// normalises a word into its key
+func (opts keyOptions) normKey(s string) string {
+	if opts == (keyOptions{}) {
+		return s
+	}
+
+	if opts.nfc || opts.diacritics {
+		s = norm.NFC.String(s)
+	}
+
+	if opts.diacritics {
+		t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+		if out, _, err := transform.String(t, s); err == nil {
+			s = out
+		}
+	}
+
+	if opts.fold {
+		s = strings.ToLower(s)
+	}
+
+	if opts.apostrophes {
+		s = strings.Trim(apostropheReplacer.Replace(s), "'")
+	}
+
+	if opts.hyphens {
+		s = hyphenReplacer.Replace(s)
+	}
+
+	return s
+}
+
+// normalises every word in li, dropping words that end up empty
+func (opts keyOptions) normKeys(li []string) []string {
+	if opts == (keyOptions{}) {
+		return li
+	}
+
+	keys := make([]string, 0, len(li))
+	for _, word := range li {
+		if key := opts.normKey(word); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,164 @@
+package main
+
+import "strings"
+
+// irregular forms the suffix rules can't undo
+var lemmaExceptions = map[string]string{
+	"am": "be", "are": "be", "is": "be", "was": "be", "were": "be", "been": "be", "being": "be",
+	"has": "have", "had": "have", "having": "have",
+	"does": "do", "did": "do", "done": "do",
+	"goes": "go", "went": "go", "gone": "go",
+	"made": "make", "said": "say", "took": "take", "taken": "take",
+	"gave": "give", "given": "give", "came": "come", "knew": "know", "known": "know",
+	"saw": "see", "seen": "see", "got": "get", "gotten": "get", "found": "find",
+	"thought": "think", "brought": "bring", "bought": "buy", "caught": "catch",
+	"taught": "teach", "sought": "seek", "told": "tell", "sold": "sell",
+	"held": "hold", "kept": "keep", "left": "leave", "felt": "feel", "meant": "mean",
+	"began": "begin", "begun": "begin", "ran": "run", "wrote": "write", "written": "write",
+	"spoke": "speak", "spoken": "speak", "chose": "choose", "chosen": "choose",
+	"grew": "grow", "grown": "grow", "drew": "draw", "drawn": "draw",
+	"flew": "fly", "flown": "fly", "fell": "fall", "fallen": "fall",
+	"rose": "rise", "risen": "rise", "bore": "bear", "borne": "bear", "born": "bear",
+	"wore": "wear", "worn": "wear", "tore": "tear", "torn": "tear",
+	"lay": "lie", "lain": "lie", "laid": "lay", "paid": "pay",
+	"stood": "stand", "understood": "understand", "struck": "strike",
+	"men": "man", "women": "woman", "children": "child", "feet": "foot", "teeth": "tooth",
+	"geese": "goose", "mice": "mouse", "lice": "louse", "oxen": "ox", "people": "person",
+	"dice": "die", "data": "datum", "criteria": "criterion", "phenomena": "phenomenon",
+	"better": "good", "best": "good", "worse": "bad", "worst": "bad",
+	"less": "little", "least": "little", "more": "much", "most": "much",
+}
+
+// possible lemmas of word, most likely first, from the exception list and suffix rules
+func lemmaCandidates(word string) []string {
+	var cands []string
+
+	if lemma, ok := lemmaExceptions[word]; ok {
+		cands = append(cands, lemma)
+	}
+
+	// possessives
+	if strings.HasSuffix(word, "'s") {
+		word = strings.TrimSuffix(word, "'s")
+		cands = append(cands, word)
+	} else if strings.HasSuffix(word, "s'") {
+		word = strings.TrimSuffix(word, "'")
+		cands = append(cands, word)
+	}
+
+	n := len(word)
+	if n < 4 {
+		return cands
+	}
+
+	stem := func(suffix string) string {
+		return word[:n-len(suffix)]
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ies"):
+		cands = append(cands, stem("ies")+"y", stem("s"))
+	case strings.HasSuffix(word, "ves"):
+		cands = append(cands, stem("ves")+"f", stem("ves")+"fe", stem("s"))
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "zes"),
+		strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "oes"):
+		cands = append(cands, stem("es"), stem("s"))
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		cands = append(cands, stem("s"))
+	case strings.HasSuffix(word, "ied"):
+		cands = append(cands, stem("ied")+"y")
+	case strings.HasSuffix(word, "ed"):
+		cands = append(cands, stem("ed"), stem("d"), undouble(stem("ed")))
+	case strings.HasSuffix(word, "ying"):
+		cands = append(cands, stem("ing"), stem("ying")+"ie")
+	case strings.HasSuffix(word, "ing"):
+		cands = append(cands, stem("ing"), stem("ing")+"e", undouble(stem("ing")))
+	case strings.HasSuffix(word, "iest"):
+		cands = append(cands, stem("iest")+"y")
+	case strings.HasSuffix(word, "ier"):
+		cands = append(cands, stem("ier")+"y")
+	case strings.HasSuffix(word, "est"):
+		cands = append(cands, stem("est"), stem("st"), undouble(stem("est")))
+	case strings.HasSuffix(word, "er"):
+		cands = append(cands, stem("er"), stem("r"), undouble(stem("er")))
+	}
+
+	return cands
+}
+
+// running -> run, stopped -> stop
+func undouble(stem string) string {
+	n := len(stem)
+	if n > 2 && stem[n-1] == stem[n-2] && !strings.ContainsRune("aeiouls", rune(stem[n-1])) {
+		return stem[:n-1]
+	}
+	return stem
+}
+
+// rule based lemmatiser that only maps words onto existing headwords
+// a headword is only merged if it is an inflection stub, i.e. its own definition
+// mentions the lemma ("cities" -> "of city"), so "better" stays if it is defined as a word
+type lemmatizer struct {
+	heads map[string]bool
+	stubs map[string]string
+}
+
+func newLemmatizer(defs map[string]*Definition) *lemmatizer {
+	l := &lemmatizer{heads: make(map[string]bool), stubs: make(map[string]string)}
+
+	for k := range defs {
+		l.heads[k] = true
+	}
+
+	for k, v := range defs {
+		for _, cand := range lemmaCandidates(k) {
+			if cand == k || !l.heads[cand] {
+				continue
+			}
+			for _, word := range v.words {
+				if word == cand {
+					l.stubs[k] = cand
+					break
+				}
+			}
+			if _, ok := l.stubs[k]; ok {
+				break
+			}
+		}
+	}
+
+	// only merge into real headwords, not into other stubs
+	var chained []string
+	for k, lemma := range l.stubs {
+		if _, ok := l.stubs[lemma]; ok {
+			chained = append(chained, k)
+		}
+	}
+	for _, k := range chained {
+		delete(l.stubs, k)
+	}
+
+	return l
+}
+
+func (l *lemmatizer) lemma(word string) string {
+	if lemma, ok := l.stubs[word]; ok {
+		return lemma
+	}
+
+	if l.heads[word] {
+		return word
+	}
+
+	for _, cand := range lemmaCandidates(word) {
+		if cand != word && l.heads[cand] {
+			if lemma, ok := l.stubs[cand]; ok {
+				return lemma
+			}
+			return cand
+		}
+	}
+
+	return word
+}
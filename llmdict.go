@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LLM generated dictionaries are a single JSON object mapping every concept to the list
+// of concepts that define it, see README.md
+//
+//	{"God": ["Creator", "Eternal", "Soul"], "Creator": ["Existence", "Genesis"]}
+//
+// models get this wrong in a few typical ways, readLLMDict catches them before solving
+
+// one key of the object, in file order
+type llmEntry struct {
+	name  string
+	words []string
+}
+
+// problems found in an LLM dictionary, written to llmReport.json
+type llmReport struct {
+	// keys that appear more than once, their lists are joined
+	Duplicates []string `json:"duplicates"`
+	// keys with an empty list, they become free words
+	Empty []string `json:"empty"`
+	// keys that list themselves
+	SelfRefs []string `json:"selfRefs"`
+	// words that only differ in capitalisation, like God and god
+	CaseCollisions [][]string `json:"caseCollisions"`
+	// words used in lists that are never keys
+	Undefined []string `json:"undefined"`
+}
+
+// whether the dictionary has anything wrong besides undefined words, which are allowed
+func (rep llmReport) malformed() bool {
+	return len(rep.Duplicates) != 0 || len(rep.Empty) != 0 || len(rep.SelfRefs) != 0 || len(rep.CaseCollisions) != 0
+}
+
+// reads the object token by token, encoding/json would silently keep the last duplicate
+func readLLMDict(fn string) ([]llmEntry, error) {
+	file, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+
+	expect := func(want json.Delim) error {
+		tkn, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tkn != want {
+			return fmt.Errorf("%s: expected %s at offset %d, got %v", fn, want, dec.InputOffset(), tkn)
+		}
+		return nil
+	}
+
+	if err := expect('{'); err != nil {
+		return nil, err
+	}
+
+	var entries []llmEntry
+
+	for dec.More() {
+		tkn, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name := tkn.(string) // object keys are always strings
+
+		if err := expect('['); err != nil {
+			return nil, fmt.Errorf("%q: %w", name, err)
+		}
+
+		words := []string{}
+		for dec.More() {
+			tkn, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			word, ok := tkn.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: %q lists %v, not a word, at offset %d", fn, name, tkn, dec.InputOffset())
+			}
+			words = append(words, word)
+		}
+
+		if err := expect(']'); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, llmEntry{name: name, words: words})
+	}
+
+	if err := expect('}'); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func validateLLMDict(entries []llmEntry) llmReport {
+	rep := llmReport{Duplicates: []string{}, Empty: []string{}, SelfRefs: []string{}, CaseCollisions: [][]string{}, Undefined: []string{}}
+
+	keys := make(map[string]int)
+	for _, e := range entries {
+		keys[e.name]++
+		if keys[e.name] == 2 {
+			rep.Duplicates = append(rep.Duplicates, e.name)
+		}
+	}
+
+	undefined := make(map[string]bool)
+	// lower case -> spellings
+	spellings := make(map[string]map[string]bool)
+	spell := func(word string) {
+		low := strings.ToLower(word)
+		if spellings[low] == nil {
+			spellings[low] = make(map[string]bool)
+		}
+		spellings[low][word] = true
+	}
+
+	for _, e := range entries {
+		spell(e.name)
+
+		if len(e.words) == 0 {
+			rep.Empty = append(rep.Empty, e.name)
+		}
+
+		self := false
+		for _, word := range e.words {
+			spell(word)
+			if word == e.name {
+				self = true
+			}
+			if keys[word] == 0 {
+				undefined[word] = true
+			}
+		}
+		if self {
+			rep.SelfRefs = append(rep.SelfRefs, e.name)
+		}
+	}
+
+	for _, words := range spellings {
+		if len(words) < 2 {
+			continue
+		}
+		var collision []string
+		for word := range words {
+			collision = append(collision, word)
+		}
+		sort.Strings(collision)
+		rep.CaseCollisions = append(rep.CaseCollisions, collision)
+	}
+	sort.Slice(rep.CaseCollisions, func(i, j int) bool {
+		return rep.CaseCollisions[i][0] < rep.CaseCollisions[j][0]
+	})
+
+	for word := range undefined {
+		rep.Undefined = append(rep.Undefined, word)
+	}
+	sort.Strings(rep.Undefined)
+
+	return rep
+}
+
+// loads fn reporting whatever validateLLMDict finds, duplicate keys have their lists joined
+func (d *Dictionary) loadLLM(fn string) error {
+	entries, err := readLLMDict(fn)
+	if err != nil {
+		return err
+	}
+
+	rep := validateLLMDict(entries)
+
+	fmt.Println("duplicate keys: ", len(rep.Duplicates))
+	fmt.Println("empty lists: ", len(rep.Empty))
+	fmt.Println("self references: ", len(rep.SelfRefs))
+	fmt.Println("capitalisation collisions: ", len(rep.CaseCollisions))
+	fmt.Println("undefined words: ", len(rep.Undefined))
+	if rep.malformed() {
+		fmt.Println("dictionary is malformed, see " + d.folder + "llmReport.json")
+	}
+
+	writeJSON(rep, d.folder+"llmReport.json")
+
+	defs := make(map[string][]string)
+	var order []string
+	for _, e := range entries {
+		if _, ok := defs[e.name]; !ok {
+			order = append(order, e.name)
+		}
+		defs[e.name] = append(defs[e.name], e.words...)
+	}
+
+	for _, name := range order {
+		d.addDef(name, defs[name])
+	}
+
+	return nil
+}
@@ -1,18 +1,48 @@
 package main
 
+import "flag"
+
 func main() {
+	flag.Parse()
+
+	//handleServer("wnSol.json", keyOptions{})
 
-	//handleServer("wnSol.json")
+	//wrangleDict(defaultNormalizer())
 
-	dict := LoadLLMDict()
+	// -table loads a csv, tsv or jsonl dictionary instead, see table.go
+	dict := tableOr(func() dictInterface { return LoadLLMDict("wrangle/llmgen/gd.json") })
 	//dict := LoadWNDict()
+	//dict := LoadWNSenseDict()
+	//dict := LoadWNDB("wrangle/wordnet/dict/", false)
+	//dict := LoadKaikki("wrangle/kaikki/kaikki.org-dictionary-English.jsonl", kaikkiOptions{langs: map[string]bool{"en": true}})
+	//dict := LoadDictd("wrangle/dictd/gcide")
+	//dict := LoadText("wrangle/text/dict.txt", ":")
+	//dict := mergeDicts("data/merged/", mergeOptions{policy: unionMerge, tag: true}, LoadDict(), LoadWNDict())
+
+	//dict.setKeyOptions(keyOptions{fold: true, nfc: true})
+
+	//dict.setBuildOptions(buildOptions{lemmatize: true})
+	//dict.setBuildOptions(buildOptions{phrases: true})
+	//dict.setBuildOptions(buildOptions{numbers: dropUndef, inflections: remapUndef, typos: remapUndef})
+	//dict.setBuildOptions(buildOptions{stopwords: functionWords, stopPolicy: knownStop})
+	//dict.filterPOS("n", "v")
 
 	Solve(dict)
 
+	//rollUpSenses(dict, "delNodes.json")
+
+	//posReport(dict, "delNodes.json")
+
 	//reconstructWord(dict, "happy", "delNodes.json")
 
+	//exportTree(dict, "happy", "delNodes.json")
+
+	//learnWord(dict, "happy", "cullNodes.json")
+
 	//exportSol(dict, "delNodes.json", "oldSol.json")
 
+	//exportSolStream(dict, "delNodes.json", "oldSol.jsonl")
+
 	//simulatedAnnealing(dict, "delNodes.json")
 
 	//cullSolution(dict, "delNodes.json")
@@ -21,6 +51,12 @@ func main() {
 
 	//alternateVerify(dict, "delNodes.json")
 
+	//closureQuery(dict, "delNodes.json")
+
+	//exportCurriculum(dict, "delNodes.json")
+
+	//solveGlossary(dict, "data/old/delNodes.json")
+
 	//dictVerify(dict, "cullNodes.json")
 
 	//exportTrees(dict, "delNodes.json")
@@ -30,4 +66,6 @@ func main() {
 	//exportJson(dict)
 
 	//exportCSV(dict, "delNodes.json")
+
+	//exportGraph(dict, "delNodes.json", "graphml")
 }
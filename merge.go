@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// combines several dictionaries into one graph, like WordNet glosses plus the bragitoff
+// dictionary or a base dictionary plus a domain glossary
+
+// which definition a word gets when more than one source defines it
+type mergePolicy int
+
+const (
+	unionMerge    mergePolicy = iota // the definitions of every source are joined
+	priorityMerge                    // the first source defining the word wins
+)
+
+type mergeOptions struct {
+	policy mergePolicy
+	// write the sources of every edge to edgeTags.json, as headword -> word -> sources
+	tag bool
+}
+
+// a Dictionary built from other dictionaries that remembers where each edge came from
+type mergedDict struct {
+	*Dictionary
+
+	opts    mergeOptions
+	sources []dictInterface
+	names   []string
+	// name -> word -> sources the edge word -> name came from
+	tags map[string]map[string][]string
+	// name -> sources its definition was taken from
+	from map[string][]int
+}
+
+// sources should be loaded and have their key and build options set, WordNet in sense mode
+// can't be merged since its vertices are synsets, not words
+func mergeDicts(folder string, opts mergeOptions, sources ...dictInterface) *mergedDict {
+	start := time.Now()
+
+	fmt.Println("merging dictionaries...")
+
+	m := &mergedDict{
+		Dictionary: &Dictionary{definitions: make(map[string]*Definition)},
+		opts:       opts,
+		sources:    sources,
+		tags:       make(map[string]map[string][]string),
+		from:       make(map[string][]int),
+	}
+
+	m.setFolder(folder)
+
+	err := os.MkdirAll(folder, 0755)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
+	defs := make(map[string][]string)
+
+	for i, src := range sources {
+		// sources are named after their folder, data/wn/ -> wn
+		name := filepath.Base(strings.TrimSuffix(src.getFolder(), "/"))
+		for _, other := range m.names {
+			if other == name {
+				name = name + "#" + strconv.Itoa(i+1)
+				break
+			}
+		}
+		m.names = append(m.names, name)
+
+		for _, k := range src.getNames() {
+			if _, ok := defs[k]; ok && opts.policy == priorityMerge {
+				continue
+			}
+
+			words := src.defWords(k)
+			defs[k] = append(defs[k], words...)
+			m.from[k] = append(m.from[k], i)
+
+			if m.tags[k] == nil {
+				m.tags[k] = make(map[string][]string)
+			}
+			for _, word := range words {
+				tags := m.tags[k][word]
+				if len(tags) == 0 || tags[len(tags)-1] != name {
+					m.tags[k][word] = append(tags, name)
+				}
+			}
+		}
+	}
+
+	for k, words := range defs {
+		m.addDef(k, words)
+	}
+
+	m.PrintSize()
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+
+	fmt.Println()
+
+	return m
+}
+
+// the definitions of k from the sources it was merged from, labelled by source
+func (m *mergedDict) getDef(k string) string {
+	var str string = ""
+
+	for _, i := range m.from[k] {
+		str = str + "[" + m.names[i] + "]\n" + m.sources[i].getDef(k) + "\n"
+	}
+
+	return str
+}
+
+// Dictionary.export with the definitions labelled like getDef, the embedded export
+// only sees Dictionary.getDef
+func (m *mergedDict) export(delNodes []string) (map[string][]string, error) {
+	set, err := m.Dictionary.export(delNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, sol := range set {
+		sol[0] = m.getDef(k)
+	}
+
+	return set, nil
+}
+
+// writes one solRecord per definition to out, labelled like getDef
+func (m *mergedDict) exportStream(delNodes []string, out io.Writer) error {
+	fmt.Println("exporting...")
+
+	return m.newExpander(delNodes).stream(m.getNames(), m.record, out)
+}
+
+// Helper Function : exportStream
+func (m *mergedDict) record(e *expander, k string) (solRecord, error) {
+	rec, err := m.Dictionary.record(e, k)
+	rec.Def = m.getDef(k)
+	return rec, err
+}
+
+// Dictionary.buildReport plus how many edges of g each source contributed, and
+// edgeTags.json if asked for
+func (m *mergedDict) buildReport(g *Graph) {
+	m.Dictionary.buildReport(g)
+
+	counts := make(map[string]int)
+	tagged := make(map[string]map[string][]string)
+
+	for k, words := range m.tags {
+		for word, tags := range words {
+			if word == k || !g.containsVertex(word) || !g.containsVertex(k) {
+				continue
+			}
+			for _, tag := range tags {
+				counts[tag]++
+			}
+			if len(tags) > 1 {
+				counts["shared"]++
+			}
+			if m.opts.tag {
+				if tagged[k] == nil {
+					tagged[k] = make(map[string][]string)
+				}
+				tagged[k][word] = tags
+			}
+		}
+	}
+
+	for _, name := range m.names {
+		fmt.Println("edges from "+name+": ", counts[name])
+	}
+	fmt.Println("edges from more than one source: ", counts["shared"])
+
+	if m.opts.tag {
+		writeJSON(tagged, m.folder+"edgeTags.json")
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// reads fn into memory where mmap isn't available
+func mapFile(fn string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() error { return nil }, nil
+}
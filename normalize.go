@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// cleaning applied to raw definition text before it is split into words
+type normalizer struct {
+	punct     string          // characters removed outright
+	fold      bool            // lower case everything
+	numbers   bool            // drop words that are numbers
+	stopwords map[string]bool // words dropped from definitions
+}
+
+// characters wrangle.py strips from the bragitoff csv files
+const wranglePunct = ";,.-\"[]:/!&?*~=`+#¡–^${}\\|<>£"
+
+// the cleaning wrangle.py does
+func defaultNormalizer() *normalizer {
+	return &normalizer{punct: wranglePunct, fold: true}
+}
+
+// strips punctuation and folds case
+func (n *normalizer) clean(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(n.punct, r) {
+			return -1
+		}
+		return r
+	}, s)
+
+	if n.fold {
+		s = strings.ToLower(s)
+	}
+
+	return s
+}
+
+// splits cleaned text into words, dropping numbers and stopwords if asked to
+func (n *normalizer) words(s string) []string {
+	words := []string{}
+
+	for _, word := range strings.Fields(s) {
+		if n.numbers && isNumber(word) {
+			continue
+		}
+		if n.stopwords[word] {
+			continue
+		}
+		words = append(words, word)
+	}
+
+	return words
+}
+
+// cleans and splits s into words
+func (n *normalizer) tokens(s string) []string {
+	return n.words(n.clean(s))
+}
+
+// digits with separators like 41, 3.5 and 1,000, not inf or nan
+var numberRegex = regexp.MustCompile(`^[0-9][0-9,.]*$`)
+
+func isNumber(s string) bool {
+	return numberRegex.MatchString(s)
+}
+
+// reads a stopword list, one word per line
+func loadStopwords(fn string) map[string]bool {
+	stop := make(map[string]bool)
+
+	bytes, err := os.ReadFile(fn)
+	if err != nil {
+		fmt.Print(err)
+		return stop
+	}
+
+	for _, word := range strings.Fields(string(bytes)) {
+		stop[word] = true
+	}
+
+	return stop
+}
+
+// Go port of wrangle.py, cleans src/A.csv .. src/Z.csv into dst/A.json .. dst/Z.json
+func (n *normalizer) wrangleCSV(src string, dst string) error {
+	for ch := 'A'; ch <= 'Z'; ch++ {
+		fmt.Println(string(ch))
+
+		defs, err := n.readCSV(src + string(ch) + ".csv")
+		if err != nil {
+			return err
+		}
+
+		b, err := json.MarshalIndent(defs, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(dst+string(ch)+".json", b, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Helper Function : wrangleCSV
+// every row holds "word (type) definition" in its first column
+func (n *normalizer) readCSV(fn string) (map[string][]string, error) {
+	file, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// the csv files are Windows-1252, which is how wrangle.py read them
+	reader := csv.NewReader(charmap.Windows1252.NewDecoder().Reader(file))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	defs := make(map[string][]string)
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(row) == 0 {
+			continue
+		}
+
+		name, defn, ok := strings.Cut(n.clean(row[0]), " ")
+		if !ok {
+			continue
+		}
+
+		// remove word type from definition
+		if i := strings.Index(defn, ")"); i >= 0 {
+			defn = defn[i+1:]
+		}
+
+		defn = strings.ReplaceAll(defn, "(", "")
+		defn = strings.ReplaceAll(defn, ")", "")
+
+		defs[name] = n.words(defn)
+	}
+
+	return defs, nil
+}
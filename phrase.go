@@ -0,0 +1,74 @@
+package main
+
+import "strings"
+
+// multi-word headwords like "ice cream" or "give up" are single vertices, definitions
+// are tokenised by matching the longest phrase headword at every word
+
+// phrase headwords written with underscores, like WordNet does, use spaces instead
+func phraseKey(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == '_' || r == ' '
+	}), " ")
+}
+
+// longest match lookup of phrase headwords
+type phraseMatcher struct {
+	// phraseKey of the headword -> headword as it is written
+	phrases map[string]string
+	// most words in any phrase
+	maxLen int
+}
+
+func newPhraseMatcher(names []string) *phraseMatcher {
+	m := &phraseMatcher{phrases: make(map[string]string)}
+
+	for _, name := range names {
+		key := phraseKey(name)
+		n := len(strings.Fields(key))
+		if n < 2 {
+			continue
+		}
+		m.phrases[key] = name
+		if n > m.maxLen {
+			m.maxLen = n
+		}
+	}
+
+	return m
+}
+
+// number of words of the longest phrase starting at words[i], 1 if there is none
+func (m *phraseMatcher) span(words []string, i int) int {
+	n := m.maxLen
+	if len(words)-i < n {
+		n = len(words) - i
+	}
+	for ; n > 1; n-- {
+		if _, ok := m.phrases[strings.Join(words[i:i+n], " ")]; ok {
+			return n
+		}
+	}
+	return 1
+}
+
+// groups words into phrase headwords where they match, longest first
+// a group is written like its headword, so "ice cream" can become ice_cream
+func (m *phraseMatcher) match(words []string) []string {
+	if m.maxLen == 0 {
+		return words
+	}
+
+	grouped := make([]string, 0, len(words))
+	for i := 0; i < len(words); {
+		n := m.span(words, i)
+		if n > 1 {
+			grouped = append(grouped, m.phrases[strings.Join(words[i:i+n], " ")])
+		} else {
+			grouped = append(grouped, words[i])
+		}
+		i += n
+	}
+
+	return grouped
+}
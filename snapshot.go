@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// binary snapshot of a built graph so commands don't have to call AddData every time
+// all numbers are little endian, the layout is
+//
+//	header    magic "DICTSNAP", version uint32, reserved uint32, sha256 of the dictionary,
+//	          vertex count, edge count, stopped word count, key bytes (uint64 each)
+//	keys      vertex count + stopped count + 1 uint64 offsets, then the key bytes,
+//	          padded to 8 bytes, vertices come first in sorted order
+//	edges     vertex count + 1 uint64 row offsets, then an uint32 target per edge (CSR)
+//
+// in-lists aren't stored, they are rebuilt from the out-lists when loading
+
+const snapMagic = "DICTSNAP"
+
+const snapVersion = 1
+
+const snapHeaderLen = 8 + 4 + 4 + 32 + 4*8
+
+var errStaleSnapshot = errors.New("snapshot is stale")
+
+// hash of everything AddData builds the graph from, a snapshot with a different
+// hash was made from another dictionary or with other options
+func dictHash(dict dictInterface) [32]byte {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "%s\x00%+v\x00%+v\x00", dict.getFolder(), dict.getBuildOptions(), dict.getKeyOptions())
+
+	names := dict.getNames()
+
+	// WordNet's vertices also depend on sense mode, the POS filter and phrases
+	if wn, ok := dict.(*WNdict); ok {
+		fmt.Fprintf(h, "%v\x00%v\x00%v\x00", wn.senses, wn.pos, wn.phrases)
+
+		// in sense mode the vertices are synsets, defWords takes their IDs
+		if wn.senses {
+			names = names[:0]
+			for ID := range wn.IDMappings {
+				names = append(names, ID)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(strings.Join(dict.defWords(name), "\x01")))
+		h.Write([]byte{0})
+	}
+
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+func writeSnapshot(g *Graph, hash [32]byte, fn string) error {
+	keys := g.sortedKeys()
+
+	index := make(map[string]uint32, len(keys))
+	for i, k := range keys {
+		index[k] = uint32(i)
+	}
+
+	var stopped []string
+	for k := range g.stopped {
+		stopped = append(stopped, k)
+	}
+	sort.Strings(stopped)
+
+	var keyLen, edges uint64
+	for _, k := range keys {
+		keyLen += uint64(len(k))
+		edges += uint64(modLen(g.vertices[k].outList))
+	}
+	for _, k := range stopped {
+		keyLen += uint64(len(k))
+	}
+
+	le := binary.LittleEndian
+
+	buf := make([]byte, 0, snapHeaderLen+8*(len(keys)+len(stopped)+1)+int(keyLen)+8*(len(keys)+2)+4*int(edges))
+
+	buf = append(buf, snapMagic...)
+	buf = le.AppendUint32(buf, snapVersion)
+	buf = le.AppendUint32(buf, 0)
+	buf = append(buf, hash[:]...)
+	for _, n := range []uint64{uint64(len(keys)), edges, uint64(len(stopped)), keyLen} {
+		buf = le.AppendUint64(buf, n)
+	}
+
+	all := append(keys[:len(keys):len(keys)], stopped...)
+	var off uint64
+	for _, k := range all {
+		buf = le.AppendUint64(buf, off)
+		off += uint64(len(k))
+	}
+	buf = le.AppendUint64(buf, off)
+	for _, k := range all {
+		buf = append(buf, k...)
+	}
+	buf = append(buf, make([]byte, (8-len(buf)%8)%8)...)
+
+	var row uint64
+	for _, k := range keys {
+		buf = le.AppendUint64(buf, row)
+		row += uint64(modLen(g.vertices[k].outList))
+	}
+	buf = le.AppendUint64(buf, row)
+	for _, k := range keys {
+		for _, out := range g.vertices[k].outList {
+			if out.key != "" {
+				buf = le.AppendUint32(buf, index[out.key])
+			}
+		}
+	}
+
+	return os.WriteFile(fn, buf, 0644)
+}
+
+// loads a snapshot, failing with errStaleSnapshot if it wasn't made with hash
+func readSnapshot(fn string, hash [32]byte) (*Graph, error) {
+	data, unmap, err := mapFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer unmap()
+
+	if len(data) < snapHeaderLen || string(data[:8]) != snapMagic {
+		return nil, fmt.Errorf("%s: not a graph snapshot", fn)
+	}
+
+	le := binary.LittleEndian
+
+	if v := le.Uint32(data[8:]); v != snapVersion {
+		return nil, fmt.Errorf("%s: snapshot version %d, want %d", fn, v, snapVersion)
+	}
+	if !bytes.Equal(data[16:48], hash[:]) {
+		return nil, errStaleSnapshot
+	}
+
+	nVert := le.Uint64(data[48:])
+	nEdge := le.Uint64(data[56:])
+	nStop := le.Uint64(data[64:])
+	keyLen := le.Uint64(data[72:])
+
+	// counts past the file size would overflow the offsets below
+	size := uint64(len(data))
+	if nVert > size || nEdge > size || nStop > size || keyLen > size {
+		return nil, fmt.Errorf("%s: snapshot is corrupted", fn)
+	}
+
+	offStart := uint64(snapHeaderLen)
+	keyStart := offStart + 8*(nVert+nStop+1)
+	rowStart := keyStart + keyLen
+	rowStart += (8 - rowStart%8) % 8
+	colStart := rowStart + 8*(nVert+1)
+	if colStart+4*nEdge != size {
+		return nil, fmt.Errorf("%s: snapshot is truncated", fn)
+	}
+
+	// offsets have to start at 0, never decrease and end at the number of bytes or edges
+	monotonic := func(start uint64, n uint64, last uint64) bool {
+		var prev uint64
+		for i := uint64(0); i <= n; i++ {
+			off := le.Uint64(data[start+8*i:])
+			if off < prev || (i == 0 && off != 0) {
+				return false
+			}
+			prev = off
+		}
+		return prev == last
+	}
+	if !monotonic(offStart, nVert+nStop, keyLen) || !monotonic(rowStart, nVert, nEdge) {
+		return nil, fmt.Errorf("%s: snapshot is corrupted", fn)
+	}
+	for e := uint64(0); e < nEdge; e++ {
+		if uint64(le.Uint32(data[colStart+4*e:])) >= nVert {
+			return nil, fmt.Errorf("%s: snapshot is corrupted", fn)
+		}
+	}
+
+	key := func(i uint64) string {
+		from := le.Uint64(data[offStart+8*i:])
+		to := le.Uint64(data[offStart+8*(i+1):])
+		return string(data[keyStart+from : keyStart+to])
+	}
+
+	g := &Graph{vertices: make(map[string]*Vertex, nVert), pqMap: make(map[string]*Item)}
+
+	verts := make([]*Vertex, nVert)
+	for i := range verts {
+		verts[i] = &Vertex{key: key(uint64(i))}
+		g.vertices[verts[i].key] = verts[i]
+	}
+	for i := nVert; i < nVert+nStop; i++ {
+		g.stop(key(i))
+	}
+
+	for i, v := range verts {
+		from := le.Uint64(data[rowStart+8*uint64(i):])
+		to := le.Uint64(data[rowStart+8*uint64(i+1):])
+		v.outList = make([]*Vertex, 0, to-from)
+		for e := from; e < to; e++ {
+			out := verts[le.Uint32(data[colStart+4*e:])]
+			v.outList = append(v.outList, out)
+			out.inList = append(out.inList, v)
+		}
+	}
+
+	return g, nil
+}
+
+// builds the graph of dict, reusing folder+"graph.snap" if it was made from the same
+// dictionary and options and writing it otherwise
+func buildGraph(dict dictInterface) *Graph {
+	fn := dict.getFolder() + "graph.snap"
+	hash := dictHash(dict)
+
+	start := time.Now()
+
+	g, err := readSnapshot(fn, hash)
+	if err == nil {
+		fmt.Println("loaded graph snapshot: ", time.Since(start))
+		return g
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		fmt.Println("rebuilding graph snapshot: ", err)
+	}
+
+	g = &Graph{vertices: make(map[string]*Vertex), pqMap: make(map[string]*Item)}
+
+	dict.AddData(g)
+
+	err = writeSnapshot(g, hash, fn)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
+	return g
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func snapGraph() *Graph {
+	g := &Graph{vertices: make(map[string]*Vertex), pqMap: make(map[string]*Item)}
+	for _, k := range []string{"animal", "dog", "living", "thing"} {
+		g.AddVertex(k)
+	}
+	g.AddEdge("animal", "dog")
+	g.AddEdge("living", "animal")
+	g.AddEdge("thing", "animal")
+	g.AddEdge("thing", "living")
+	g.AddEdge("animal", "thing")
+	g.stop("the")
+	return g
+}
+
+// out-lists of every vertex, sorted
+func snapEdges(g *Graph) map[string][]string {
+	edges := make(map[string][]string)
+	for k, v := range g.vertices {
+		edges[k] = []string{}
+		for _, out := range v.outList {
+			edges[k] = append(edges[k], out.key)
+		}
+		sort.Strings(edges[k])
+	}
+	return edges
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "graph.snap")
+	hash := [32]byte{1, 2, 3}
+	g := snapGraph()
+
+	if err := writeSnapshot(g, hash, fn); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := readSnapshot(fn, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := snapEdges(loaded), snapEdges(g); !reflect.DeepEqual(got, want) {
+		t.Errorf("edges = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(loaded.stopped, g.stopped) {
+		t.Errorf("stopped = %v, want %v", loaded.stopped, g.stopped)
+	}
+	if n := modLen(loaded.vertices["animal"].inList); n != 2 {
+		t.Errorf("animal has %d in-edges, want 2", n)
+	}
+
+	if _, err := readSnapshot(fn, [32]byte{}); err != errStaleSnapshot {
+		t.Errorf("other hash: err = %v, want errStaleSnapshot", err)
+	}
+}
+
+// corrupted snapshots must fail so buildGraph rebuilds them, not panic
+func TestSnapshotCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	hash := [32]byte{1, 2, 3}
+
+	fn := filepath.Join(dir, "graph.snap")
+	if err := writeSnapshot(snapGraph(), hash, fn); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	le := binary.LittleEndian
+	nVert := le.Uint64(data[48:])
+	nStop := le.Uint64(data[64:])
+	keyLen := le.Uint64(data[72:])
+	rowStart := snapHeaderLen + 8*(nVert+nStop+1) + keyLen
+	rowStart += (8 - rowStart%8) % 8
+	colStart := rowStart + 8*(nVert+1)
+
+	cases := map[string]func(b []byte) []byte{
+		"truncated":      func(b []byte) []byte { return b[:len(b)-4] },
+		"vertex count":   func(b []byte) []byte { le.PutUint64(b[48:], 1<<62); return b },
+		"key offset":     func(b []byte) []byte { le.PutUint64(b[snapHeaderLen+8:], keyLen+1); return b },
+		"key order":      func(b []byte) []byte { le.PutUint64(b[snapHeaderLen+16:], 0); return b },
+		"row offset":     func(b []byte) []byte { le.PutUint64(b[rowStart+8:], 1<<40); return b },
+		"edge target":    func(b []byte) []byte { le.PutUint32(b[colStart:], uint32(nVert)); return b },
+		"last key":       func(b []byte) []byte { le.PutUint64(b[snapHeaderLen+8*(nVert+nStop):], 1); return b },
+		"first row":      func(b []byte) []byte { le.PutUint64(b[rowStart:], 1); return b },
+		"not a snapshot": func(b []byte) []byte { return b[:10] },
+	}
+
+	for name, corrupt := range cases {
+		b := corrupt(append([]byte{}, data...))
+		bad := filepath.Join(dir, "bad.snap")
+		if err := os.WriteFile(bad, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readSnapshot(bad, hash); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// the hash has to cover the edges, in sense mode those are between synset IDs
+func TestDictHashEdges(t *testing.T) {
+	for _, senses := range []bool{false, true} {
+		wn := &WNdict{definitions: make(map[string][]*WNdef), IDMappings: make(map[string]*WNdef), senses: senses}
+		if err := wn.loadDB("testdata/wordnet"); err != nil {
+			t.Fatal(err)
+		}
+
+		before := dictHash(wn)
+
+		// eat.v.01 is "take in solid food", point food at another synset
+		eat := wn.IDMappings["eat.v.01"]
+		eat.mappings = []string{"entity.n.01"}
+		eat.regexWords = []string{"entity"}
+
+		if dictHash(wn) == before {
+			t.Errorf("senses %v: changing an edge left the hash unchanged", senses)
+		}
+	}
+}
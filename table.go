@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loads headword/definition pairs from any csv, tsv or jsonl file so a new dictionary
+// doesn't need its own dictInterface type or wrangle script
+
+// where the headword and definition are found in each record
+type tableSpec struct {
+	format string // csv, tsv or jsonl, guessed from the file extension if empty
+	word   string // column index or header name for csv/tsv, field name for jsonl
+	def    string // empty means 0 and 1 for csv/tsv, word and gloss for jsonl
+	header bool   // the first csv/tsv row names the columns
+	folder string // where solutions are written
+}
+
+var (
+	tableFile   = flag.String("table", "", "csv, tsv or jsonl dictionary to load instead of the default one")
+	tableFormat = flag.String("format", "", "csv, tsv or jsonl, guessed from the file extension if empty")
+	tableWord   = flag.String("word", "", "column index or header name (csv/tsv) or field (jsonl) of the headword, 0 or word if empty")
+	tableDef    = flag.String("def", "", "column index or header name (csv/tsv) or field (jsonl) of the definition, 1 or gloss if empty")
+	tableHeader = flag.Bool("header", false, "the first csv/tsv row names the columns")
+	tableFolder = flag.String("out", "data/table/", "folder solutions are written to")
+)
+
+// the table dictionary asked for with -table, or load() if there is none
+func tableOr(load func() dictInterface) dictInterface {
+	if *tableFile == "" {
+		return load()
+	}
+
+	spec := tableSpec{format: *tableFormat, word: *tableWord, def: *tableDef, header: *tableHeader, folder: *tableFolder}
+
+	return LoadTable(*tableFile, spec, defaultNormalizer())
+}
+
+func LoadTable(fn string, spec tableSpec, n *normalizer) dictInterface {
+	start := time.Now()
+
+	fmt.Println("loading dictionary...")
+
+	dict := &Dictionary{definitions: make(map[string]*Definition)}
+
+	dict.setFolder(spec.folder)
+
+	err := os.MkdirAll(spec.folder, 0755)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
+	err = dict.loadTable(fn, spec, n)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
+	dict.PrintSize()
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+
+	fmt.Println()
+
+	return dict
+}
+
+// rows with the same headword are treated as senses and their words joined
+func (d *Dictionary) loadTable(fn string, spec tableSpec, n *normalizer) error {
+	file, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	format := spec.format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fn)), ".")
+	}
+
+	defs := make(map[string][]string)
+	var order []string
+
+	add := func(word string, def string) {
+		name := phraseKey(n.clean(word))
+		if name == "" {
+			return
+		}
+		if _, ok := defs[name]; !ok {
+			order = append(order, name)
+		}
+		defs[name] = append(defs[name], n.tokens(def)...)
+	}
+
+	switch format {
+	case "csv", "tsv":
+		spec.word, spec.def = tableDefault(spec.word, "0"), tableDefault(spec.def, "1")
+		err = readTableRows(file, format == "tsv", spec, add)
+	case "jsonl", "json", "ndjson":
+		spec.word, spec.def = tableDefault(spec.word, "word"), tableDefault(spec.def, "gloss")
+		err = readTableLines(file, spec, add)
+	default:
+		err = fmt.Errorf("unknown table format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		d.addDef(name, defs[name])
+	}
+
+	return nil
+}
+
+// Helper Function : loadTable
+func tableDefault(col string, def string) string {
+	if col == "" {
+		return def
+	}
+	return col
+}
+
+// Helper Function : loadTable
+func readTableRows(r io.Reader, tabs bool, spec tableSpec, add func(string, string)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if tabs {
+		reader.Comma = '\t'
+	}
+
+	var header []string
+	if spec.header {
+		row, err := reader.Read()
+		if err != nil {
+			return err
+		}
+		header = row
+	}
+
+	word, err := tableColumn(spec.word, header)
+	if err != nil {
+		return err
+	}
+	def, err := tableColumn(spec.def, header)
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if word >= len(row) || def >= len(row) {
+			continue
+		}
+		add(row[word], row[def])
+	}
+
+	return nil
+}
+
+// Helper Function : readTableRows
+// index of a column given by number or by header name
+func tableColumn(col string, header []string) (int, error) {
+	if i, err := strconv.Atoi(col); err == nil && i >= 0 {
+		return i, nil
+	}
+
+	for i, name := range header {
+		if name == col {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no column %q", col)
+}
+
+// Helper Function : loadTable
+// a definition field holding a list of strings is read as one definition per sense
+func readTableLines(r io.Reader, spec tableSpec, add func(string, string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var records, matched int
+
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		records++
+
+		word, ok := record[spec.word].(string)
+		if !ok {
+			continue
+		}
+		matched++
+
+		switch def := record[spec.def].(type) {
+		case string:
+			add(word, def)
+		case []interface{}:
+			for _, sense := range def {
+				if s, ok := sense.(string); ok {
+					add(word, s)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// most likely -word names a field the records don't have
+	if records > 0 && matched == 0 {
+		return fmt.Errorf("no record has a string field %q", spec.word)
+	}
+
+	return nil
+}
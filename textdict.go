@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// reads plain text dictionaries with one "headword: definition" entry per line
+// indented lines continue the definition above them
+
+func LoadText(fn string, sep string) dictInterface {
+	start := time.Now()
+
+	fmt.Println("loading dictionary...")
+
+	dict := &Dictionary{definitions: make(map[string]*Definition)}
+
+	dict.setFolder("data/text/")
+
+	err := os.MkdirAll(dict.folder, 0755)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
+	err = dict.loadText(fn, sep, defaultNormalizer())
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
+	dict.PrintSize()
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+
+	fmt.Println()
+
+	return dict
+}
+
+// headwords listed more than once have their definitions joined
+func (d *Dictionary) loadText(fn string, sep string, n *normalizer) error {
+	file, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	defs := make(map[string][]string)
+	var order []string
+	var name string
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// continuation of the last entry
+		if line[0] == ' ' || line[0] == '\t' {
+			if name != "" {
+				defs[name] = append(defs[name], n.tokens(line)...)
+			}
+			continue
+		}
+
+		word, defn, ok := strings.Cut(line, sep)
+		if !ok {
+			name = ""
+			continue
+		}
+
+		name = phraseKey(n.clean(word))
+		if name == "" {
+			continue
+		}
+		if _, ok := defs[name]; !ok {
+			order = append(order, name)
+		}
+		defs[name] = append(defs[name], n.tokens(defn)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		d.addDef(name, defs[name])
+	}
+
+	return nil
+}
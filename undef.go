@@ -0,0 +1,216 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// what is done with an undefined word of some class before solving
+type undefAction int
+
+const (
+	keepUndef  undefAction = iota // leave the word as a free word
+	dropUndef                     // remove the word from definitions
+	remapUndef                    // replace the word by the headword it likely stands for
+)
+
+// classes of undefined words
+const (
+	undefNumber     = "number"
+	undefInflection = "inflection"
+	undefTypo       = "typo"
+	undefLeaf       = "leaf"
+)
+
+// sorts undefined words into numbers, inflections of headwords, likely typos of
+// headwords (one edit away) and true leaves
+type undefClassifier struct {
+	heads map[string]bool
+	lem   *lemmatizer
+	// headword with one letter deleted -> headwords
+	dels map[string][]string
+	// how often each headword is used, to pick between typo candidates
+	freq map[string]int
+}
+
+// shortest word considered a possible typo, shorter words have too many neighbours
+const minTypoLen = 4
+
+func newUndefClassifier(heads map[string]bool, freq map[string]int) *undefClassifier {
+	c := &undefClassifier{
+		heads: heads,
+		lem:   &lemmatizer{heads: heads, stubs: make(map[string]string)},
+		dels:  make(map[string][]string),
+		freq:  freq,
+	}
+
+	for h := range heads {
+		if len([]rune(h)) < minTypoLen-1 {
+			continue
+		}
+		for _, del := range deletions(h) {
+			c.dels[del] = append(c.dels[del], h)
+		}
+	}
+
+	return c
+}
+
+// class of the undefined word and the headword it likely stands for, if any
+func (c *undefClassifier) classify(word string) (string, string) {
+	if isNumeral(word) {
+		return undefNumber, ""
+	}
+
+	if c.heads[word] {
+		return undefLeaf, ""
+	}
+
+	if lemma := c.lem.lemma(word); lemma != word {
+		return undefInflection, lemma
+	}
+
+	if typo := c.typo(word); typo != "" {
+		return undefTypo, typo
+	}
+
+	return undefLeaf, ""
+}
+
+// the most used headword one insertion, deletion, substitution or transposition away
+func (c *undefClassifier) typo(word string) string {
+	if len([]rune(word)) < minTypoLen {
+		return ""
+	}
+
+	cands := make(map[string]bool)
+
+	// a letter was added to the headword
+	for _, del := range deletions(word) {
+		if c.heads[del] {
+			cands[del] = true
+		}
+		// a letter was substituted or two letters were swapped
+		for _, h := range c.dels[del] {
+			if withinOneEdit(word, h) {
+				cands[h] = true
+			}
+		}
+	}
+
+	// a letter was left out of the headword
+	for _, h := range c.dels[word] {
+		cands[h] = true
+	}
+
+	best := ""
+	for h := range cands {
+		if best == "" || c.freq[h] > c.freq[best] || (c.freq[h] == c.freq[best] && h < best) {
+			best = h
+		}
+	}
+
+	return best
+}
+
+// every string made by deleting one letter of s
+func deletions(s string) []string {
+	r := []rune(s)
+	dels := make([]string, 0, len(r))
+	for i := range r {
+		dels = append(dels, string(r[:i])+string(r[i+1:]))
+	}
+	return dels
+}
+
+// whether a and b are at most one edit apart, counting a swap of neighbours as one edit
+func withinOneEdit(a string, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) != len(rb) {
+		if len(ra) > len(rb) {
+			ra, rb = rb, ra
+		}
+		if len(rb)-len(ra) > 1 {
+			return false
+		}
+		i := 0
+		for i < len(ra) && ra[i] == rb[i] {
+			i++
+		}
+		return string(ra[i:]) == string(rb[i+1:])
+	}
+
+	var diff []int
+	for i := range ra {
+		if ra[i] != rb[i] {
+			diff = append(diff, i)
+		}
+	}
+
+	switch len(diff) {
+	case 0, 1:
+		return true
+	case 2:
+		i, j := diff[0], diff[1]
+		return j == i+1 && ra[i] == rb[j] && ra[j] == rb[i]
+	}
+	return false
+}
+
+// numbers like 41, 3.5 and 1,000 and ordinals like 12th
+func isNumeral(s string) bool {
+	if isNumber(s) {
+		return true
+	}
+
+	// 1st, 2nd, 2d, 12mo, 1990s
+	for _, suffix := range []string{"st", "nd", "rd", "th", "d", "mo", "s"} {
+		if strings.HasSuffix(s, suffix) {
+			s = strings.TrimSuffix(s, suffix)
+			break
+		}
+	}
+	s = strings.ReplaceAll(s, ",", "")
+
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// undefined words of a dictionary sorted into classes, see undefReport()
+type undefReport struct {
+	Numbers     []string          `json:"numbers"`
+	Inflections map[string]string `json:"inflections"`
+	Typos       map[string]string `json:"typos"`
+	Leaves      []string          `json:"leaves"`
+}
+
+func (c *undefClassifier) report(words []string) undefReport {
+	rep := undefReport{Inflections: make(map[string]string), Typos: make(map[string]string)}
+
+	for _, word := range words {
+		class, head := c.classify(word)
+		switch class {
+		case undefNumber:
+			rep.Numbers = append(rep.Numbers, word)
+		case undefInflection:
+			rep.Inflections[word] = head
+		case undefTypo:
+			rep.Typos[word] = head
+		default:
+			rep.Leaves = append(rep.Leaves, word)
+		}
+	}
+
+	sort.Strings(rep.Numbers)
+	sort.Strings(rep.Leaves)
+
+	return rep
+}
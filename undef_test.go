@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+// "rising" is a typo of "arising", which lemmatize merges into "arise", so the remap
+// has to follow it there or arising is left in the graph as a free word
+func TestRemapMergedHead(t *testing.T) {
+	d := &Dictionary{definitions: make(map[string]*Definition)}
+	d.addDef("arise", []string{"to", "get", "up", "rising"})
+	d.addDef("arising", []string{"of", "arise"})
+	d.setBuildOptions(buildOptions{lemmatize: true, typos: remapUndef})
+
+	g := &Graph{vertices: make(map[string]*Vertex), pqMap: make(map[string]*Item)}
+	d.AddData(g)
+	g.pqInit()
+
+	if head := d.undef["rising"]; head != "arise" {
+		t.Errorf("rising remapped to %q, want arise", head)
+	}
+	if !d.verify(g.FVS()) {
+		t.Error("solution doesn't verify")
+	}
+}
@@ -9,12 +9,29 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// rebuilds wrangle/cleaned/ from the raw csv files in wrangle/dict/, replaces wrangle.py
+func wrangleDict(n *normalizer) {
+	start := time.Now()
+
+	fmt.Println("wrangling dictionary...")
+
+	err := n.wrangleCSV("wrangle/dict/", "wrangle/cleaned/")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+}
+
 func LoadDict() dictInterface {
 	start := time.Now()
 
@@ -39,7 +56,8 @@ func LoadDict() dictInterface {
 	return dict
 }
 
-func LoadLLMDict() dictInterface {
+// loads an LLM generated dictionary like wrangle/llmgen/gd.json, see llmdict.go
+func LoadLLMDict(fn string) dictInterface {
 	start := time.Now()
 
 	fmt.Println("loading dictionary...")
@@ -48,8 +66,11 @@ func LoadLLMDict() dictInterface {
 
 	dict.setFolder("data/llmgen/")
 
-	dict.loadData("../llmgen/gd.json")
-	
+	err := dict.loadLLM(fn)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+
 	dict.PrintSize()
 
 	t := time.Now()
@@ -61,13 +82,73 @@ func LoadLLMDict() dictInterface {
 	return dict
 }
 
-func LoadWNDict() dictInterface {
+func LoadWNDict() *WNdict {
 	start := time.Now()
 
 	fmt.Println("loading dictionary...")
 
 	dict := &WNdict{definitions: make(map[string][]*WNdef), IDMappings: make(map[string]*WNdef)}
 
+	dict.setFolder("data/wn/")
+
+	dict.loadData("wn.json")
+
+	dict.PrintSize()
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+
+	fmt.Println()
+
+	return dict
+}
+
+// loads WordNet straight from the database files (index.noun, data.noun, ...) in dir
+// with phrases, multi-word synsets like ice_cream.n.01 become "ice cream" headwords
+func LoadWNDB(dir string, phrases bool) *WNdict {
+	start := time.Now()
+
+	fmt.Println("loading dictionary...")
+
+	dict := &WNdict{definitions: make(map[string][]*WNdef), IDMappings: make(map[string]*WNdef)}
+
+	dict.setFolder("data/wn/")
+
+	dict.phrases = phrases
+
+	err := dict.loadDB(dir)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	dict.PrintSize()
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+
+	fmt.Println()
+
+	return dict
+}
+
+// loads WordNet with one vertex per synset instead of per lemma
+func LoadWNSenseDict() *WNdict {
+	start := time.Now()
+
+	fmt.Println("loading dictionary...")
+
+	dict := &WNdict{definitions: make(map[string][]*WNdef), IDMappings: make(map[string]*WNdef), senses: true}
+
+	dict.setFolder("data/wn/senses/")
+
+	// solutions are kept apart from the lemma ones, the folder isn't in the repository
+	err := os.MkdirAll(dict.folder, 0755)
+	if err != nil {
+		fmt.Print(err)
+	}
+
 	dict.loadData("wn.json")
 
 	dict.PrintSize()
@@ -93,7 +174,20 @@ func write(li []string, fn string) {
 	}
 }
 
-func getNodes(fn string) []string {
+func writeJSON(v any, fn string) {
+	json, err := json.MarshalIndent(v, "", " ")
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	} else {
+		err = os.WriteFile(fn, json, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// reads a word list, keyed with keys like the dictionary it belongs to
+func getNodes(fn string, keys keyOptions) []string {
 	file, err := os.Open(fn)
 	if err != nil {
 		fmt.Println("error loading json")
@@ -116,56 +210,217 @@ func getNodes(fn string) []string {
 
 	json.Unmarshal(bytes, &myData)
 
-	return myData
+	return keys.normKeys(myData)
+}
+
+// how a solution was made, written next to delNodes.json
+type solveReport struct {
+	StopPolicy string `json:"stopPolicy"`
+	Stopwords  int    `json:"stopwords"`
+	Removed    int    `json:"removed"`
+	Free       int    `json:"free"`
 }
 
 func Solve(dict dictInterface) {
 	folder := dict.getFolder()
 
-	tGraph := &Graph{vertices: make(map[string]*Vertex), pqMap: make(map[string]*Item)}
-
-	dict.AddData(tGraph)
+	tGraph := buildGraph(dict)
 	tGraph.pqInit()
 
 	listFree := tGraph.top()
 
 	write(listFree, folder+"undefWords.json")
+	writeJSON(undefWords(dict, tGraph, listFree), folder+"undefReport.json")
+
+	// only Dictionary merges inflections and drops or remaps undefined words, and
+	// mergedDict also reports where its edges came from
+	if d, ok := dict.(interface{ buildReport(*Graph) }); ok {
+		d.buildReport(tGraph)
+	}
 
 	start := time.Now()
 
 	delNodes := tGraph.FVS()
 
+	// pre-known stopwords are part of the solution without being solved for
+	opts := dict.getBuildOptions()
+	if opts.stopPolicy == knownStop {
+		stopped := make([]string, 0, len(tGraph.stopped))
+		for k := range tGraph.stopped {
+			stopped = append(stopped, k)
+		}
+		sort.Strings(stopped)
+		delNodes = append(stopped, delNodes...)
+	}
+
 	write(delNodes, folder+"delNodes.json")
 
 	fmt.Println("nodes removed: ", len(delNodes))
 
+	writeJSON(solveReport{
+		StopPolicy: opts.stopPolicy.String(),
+		Stopwords:  len(tGraph.stopped),
+		Removed:    len(delNodes),
+		Free:       len(listFree),
+	}, folder+"solveReport.json")
+
 	t := time.Now()
 	elapsed := t.Sub(start)
 	fmt.Println("\ntime elapsed : ", elapsed)
 }
 
+// sorts the free words of g into numbers, inflections, typos and leaves
+func undefWords(dict dictInterface, g *Graph, listFree []string) undefReport {
+	fmt.Println("classifying undefined words...")
+
+	heads := make(map[string]bool)
+	for _, name := range dict.getNames() {
+		heads[name] = true
+	}
+
+	// headwords used in more definitions are the likelier typo targets
+	freq := make(map[string]int)
+	for k, v := range g.vertices {
+		freq[k] = len(v.outList)
+	}
+
+	rep := newUndefClassifier(heads, freq).report(listFree)
+
+	fmt.Println("numbers: ", len(rep.Numbers))
+	fmt.Println("inflections: ", len(rep.Inflections))
+	fmt.Println("typos: ", len(rep.Typos))
+	fmt.Println("leaves: ", len(rep.Leaves))
+
+	return rep
+}
+
+type senseReport struct {
+	Senses []string            `json:"senses"`
+	Lemmas map[string][]string `json:"lemmas"`
+}
+
+// rolls a sense level solution in fn up to lemmas, writes delLemmas.json and senseReport.json
+func rollUpSenses(dict *WNdict, fn string) {
+	folder := dict.getFolder()
+
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
+
+	lemmas := dict.lemmas(delNodes)
+
+	var names []string
+	for k := range lemmas {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	write(names, folder+"delLemmas.json")
+	writeJSON(senseReport{delNodes, lemmas}, folder+"senseReport.json")
+
+	fmt.Println("senses removed: ", len(delNodes))
+	fmt.Println("lemmas removed: ", len(names))
+}
+
+// writes how many words of the solution in fn fall in each part of speech to posReport.json
+func posReport(dict *WNdict, fn string) {
+	folder := dict.getFolder()
+
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
+
+	counts := dict.posCounts(delNodes)
+
+	writeJSON(counts, folder+"posReport.json")
+
+	fmt.Println("nodes removed: ", len(delNodes))
+	for pos, n := range counts {
+		fmt.Println(pos+": ", n)
+	}
+}
+
 func reconstructWord(dict dictInterface, word string, fn string) {
+	word = dict.getKeyOptions().normKey(word)
+
 	folder := dict.getFolder()
 
-	delNodes := getNodes(folder + fn)
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
 
 	defn := dict.getDef(word)
 
 	fmt.Println(defn)
 
-	defn = dict.expandDef(delNodes, word)
+	defn, err := dict.expandDef(delNodes, word)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		return
+	}
 
 	fmt.Println(defn)
 }
 
+// writes the expansion tree of word against the solution in fn to <word>Tree.json
+func exportTree(dict dictInterface, word string, fn string) {
+	word = dict.getKeyOptions().normKey(word)
+
+	folder := dict.getFolder()
+
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
+
+	tree, err := dict.expandTree(delNodes, word)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		return
+	}
+
+	writeJSON(tree, folder+word+"Tree.json")
+}
+
+type targetResult struct {
+	Target string   `json:"target"`
+	Extra  []string `json:"extra"`
+	Tree   expGraph `json:"tree"`
+}
+
+// finds the extra words to learn on top of the word set in fn to define word
+func learnWord(dict dictInterface, word string, fn string) {
+	word = dict.getKeyOptions().normKey(word)
+
+	folder := dict.getFolder()
+
+	known := getNodes(folder+fn, dict.getKeyOptions())
+
+	tGraph := buildGraph(dict)
+
+	start := time.Now()
+
+	extra, tree := tGraph.targetFVS(word, known)
+
+	writeJSON(targetResult{word, extra, tree}, folder+word+"Target.json")
+
+	fmt.Println("extra words: ", extra)
+
+	defn, err := dict.expandDef(append(known, extra...), word)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	} else {
+		fmt.Println(defn)
+	}
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+}
+
 func exportSol(dict dictInterface, fn string, fn2 string) {
 	start := time.Now()
 
 	folder := dict.getFolder()
 
-	delNodes := getNodes(folder + fn)
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
 
-	m := dict.export(delNodes)
+	m, err := dict.export(delNodes)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		return
+	}
 
 	b, err := json.MarshalIndent(m, "", "")
 
@@ -183,16 +438,46 @@ func exportSol(dict dictInterface, fn string, fn2 string) {
 	fmt.Println("\ntime elapsed : ", elapsed)
 }
 
-func cullSolution(dict dictInterface, fn string) {
+// streams the solution to data/sol/ as JSON Lines, one object per word
+func exportSolStream(dict dictInterface, fn string, fn2 string) {
+	start := time.Now()
+
 	folder := dict.getFolder()
 
-	tGraph := &Graph{vertices: make(map[string]*Vertex)}
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
+
+	file, err := os.Create("data/sol/" + fn2)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+
+	err = dict.exportStream(delNodes, w)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		return
+	}
+
+	err = w.Flush()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	dict.AddData(tGraph)
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+}
+
+func cullSolution(dict dictInterface, fn string) {
+	folder := dict.getFolder()
+
+	tGraph := buildGraph(dict)
 
 	listFree := tGraph.top()
 
-	delNodes := getNodes(folder + fn)
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
 
 	start := time.Now()
 
@@ -210,13 +495,11 @@ func cullSolution(dict dictInterface, fn string) {
 func simulatedAnnealing(dict dictInterface, fn string) {
 	folder := dict.getFolder()
 
-	tGraph := &Graph{vertices: make(map[string]*Vertex)}
-
-	dict.AddData(tGraph)
+	tGraph := buildGraph(dict)
 
 	listFree := tGraph.top()
 
-	delNodes := getNodes(folder + fn)
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
 
 	start := time.Now()
 
@@ -234,11 +517,9 @@ func simulatedAnnealing(dict dictInterface, fn string) {
 func graphVerify(dict dictInterface, fn string) {
 	folder := dict.getFolder()
 
-	delNodes := getNodes(folder + fn)
-
-	tGraph := &Graph{vertices: make(map[string]*Vertex)}
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
 
-	dict.AddData(tGraph)
+	tGraph := buildGraph(dict)
 
 	listFree := tGraph.top()
 
@@ -256,11 +537,9 @@ func graphVerify(dict dictInterface, fn string) {
 func alternateVerify(dict dictInterface, fn string) {
 	folder := dict.getFolder()
 
-	delNodes := getNodes(folder + fn)
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
 
-	tGraph := &Graph{vertices: make(map[string]*Vertex)}
-
-	dict.AddData(tGraph)
+	tGraph := buildGraph(dict)
 
 	start := time.Now()
 
@@ -283,12 +562,94 @@ func alternateVerify(dict dictInterface, fn string) {
 	fmt.Println("\ntime elapsed : ", elapsed)
 }
 
+// writes the words definable from the word set in fn, the words left undefinable
+// and the cycles blocking them to closure.json
+func closureQuery(dict dictInterface, fn string) {
+	folder := dict.getFolder()
+
+	known := getNodes(folder+fn, dict.getKeyOptions())
+
+	tGraph := buildGraph(dict)
+
+	start := time.Now()
+
+	res := tGraph.closure(known)
+
+	writeJSON(res, folder+"closure.json")
+
+	fmt.Println("definable: ", len(res.Defined))
+	fmt.Println("undefinable: ", len(res.Undefined))
+	fmt.Println("cycles: ", len(res.Cycles))
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+}
+
+// solves the glossary terms of dict against the base vocabulary in fn, a JSON list like
+// delNodes.json or a plain word list like basic English, writes glossary.json
+func solveGlossary(dict dictInterface, fn string) {
+	folder := dict.getFolder()
+
+	var known []string
+	if strings.HasSuffix(fn, ".json") {
+		known = getNodes(fn, dict.getKeyOptions())
+	} else {
+		for word := range loadStopwords(fn) {
+			known = append(known, dict.getKeyOptions().normKey(word))
+		}
+	}
+
+	tGraph := buildGraph(dict)
+
+	start := time.Now()
+
+	res := tGraph.glossary(dict.getNames(), known)
+
+	writeJSON(res, folder+"glossary.json")
+
+	fmt.Println("terms to teach: ", len(res.Teach))
+	fmt.Println("terms defined: ", len(res.Defined))
+	fmt.Println("terms known: ", len(res.Known))
+	fmt.Println("gaps: ", len(res.Gaps))
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+}
+
+// writes every word outside the solution in fn ordered by definitional depth to curriculum.json
+func exportCurriculum(dict dictInterface, fn string) {
+	folder := dict.getFolder()
+
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
+
+	tGraph := buildGraph(dict)
+
+	start := time.Now()
+
+	c, err := tGraph.curriculum(delNodes)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		return
+	}
+
+	writeJSON(c, folder+"curriculum.json")
+
+	fmt.Println("words ordered: ", len(c.Order))
+	fmt.Println("levels: ", len(c.Levels))
+
+	t := time.Now()
+	elapsed := t.Sub(start)
+	fmt.Println("\ntime elapsed : ", elapsed)
+}
+
 func dictVerify(dict dictInterface, fn string) {
 	start := time.Now()
 
 	folder := dict.getFolder()
 
-	delNodes := getNodes(folder + fn)
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
 
 	verified := dict.verify(delNodes)
 
@@ -300,7 +661,7 @@ func dictVerify(dict dictInterface, fn string) {
 }
 
 func origHandler(w http.ResponseWriter, r *http.Request) {
-	word := r.FormValue("word")
+	word := solKeys.normKey(r.FormValue("word"))
 
 	val, ok := SOL[word]
 	if ok {
@@ -312,7 +673,7 @@ func origHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func newHandler(w http.ResponseWriter, r *http.Request) {
-	word := r.FormValue("word")
+	word := solKeys.normKey(r.FormValue("word"))
 
 	val, ok := SOL[word]
 	if ok {
@@ -322,8 +683,20 @@ func newHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// parts of speech of word, only WordNet solutions carry them
+func posHandler(w http.ResponseWriter, r *http.Request) {
+	word := solKeys.normKey(r.FormValue("word"))
+
+	val, ok := SOL[word]
+	if ok && len(val) > 2 {
+		w.Write([]byte(val[2]))
+	} else {
+		w.Write([]byte(""))
+	}
+}
+
 func gHandler(w http.ResponseWriter, r *http.Request) {
-	word := r.FormValue("word")
+	word := solKeys.normKey(r.FormValue("word"))
 
 	file, err := os.Open("data/wn/trees/" + word + ".json")
 	if err != nil {
@@ -394,9 +767,12 @@ func gHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func handleServer(fn string) {
+// keys should be the key options of the dictionary fn was exported from
+func handleServer(fn string, keys keyOptions) {
 	fmt.Println("starting server...")
 
+	solKeys = keys
+
 	bytes, err := os.ReadFile("data/sol/" + fn)
 	if err != nil {
 		fmt.Print(err)
@@ -407,10 +783,19 @@ func handleServer(fn string) {
 
 	bytes = nil
 
+	if solKeys != (keyOptions{}) {
+		sol := make(map[string][]string)
+		for k, v := range SOL {
+			sol[solKeys.normKey(k)] = v
+		}
+		SOL = sol
+	}
+
 	r := mux.NewRouter()
 
 	r.HandleFunc("/orig", origHandler).Methods("GET")
 	r.HandleFunc("/new", newHandler).Methods("GET")
+	r.HandleFunc("/pos", posHandler).Methods("GET")
 	r.HandleFunc("/graph", gHandler).Methods("GET")
 
 	http.Handle("/", r)
@@ -436,13 +821,15 @@ type expGraph struct {
 
 var SOL map[string][]string
 
+// keys of the dictionary SOL was exported from, words asked for are keyed the same way
+var solKeys keyOptions
+
 func exportTrees(dict dictInterface, fn string) {
 	folder := dict.getFolder()
 
-	delNodes := getNodes(folder + fn)
+	delNodes := getNodes(folder+fn, dict.getKeyOptions())
 
-	tGraph := &Graph{vertices: make(map[string]*Vertex)}
-	dict.AddData(tGraph)
+	tGraph := buildGraph(dict)
 
 	fmt.Println("exporting trees...")
 
@@ -541,8 +928,7 @@ func exportNames(dict dictInterface) {
 func exportJson(dict dictInterface) {
 	folder := dict.getFolder()
 
-	tGraph := &Graph{vertices: make(map[string]*Vertex)}
-	dict.AddData(tGraph)
+	tGraph := buildGraph(dict)
 
 	fmt.Println("exporting graph...")
 
@@ -578,6 +964,47 @@ func exportJson(dict dictInterface) {
 	}
 }
 
+// writes the graph with vertex attributes as graph.graphml, graph.gexf or graph.dot
+// fn is the solution the in-solution flag and depth are taken from, "" for none
+func exportGraph(dict dictInterface, fn string, format string) {
+	folder := dict.getFolder()
+
+	var writeFormat func(*Graph, io.Writer, map[string]vertAttrs) error
+	switch format {
+	case "graphml":
+		writeFormat = (*Graph).writeGraphML
+	case "gexf":
+		writeFormat = (*Graph).writeGEXF
+	case "dot":
+		writeFormat = (*Graph).writeDOT
+	default:
+		fmt.Printf("Error: unknown graph format %q, want graphml, gexf or dot", format)
+		return
+	}
+
+	var delNodes []string
+	if fn != "" {
+		delNodes = getNodes(folder+fn, dict.getKeyOptions())
+	}
+
+	tGraph := buildGraph(dict)
+
+	fmt.Println("exporting graph...")
+
+	attrs := tGraph.vertAttrs(delNodes)
+
+	file, err := os.Create(folder + "graph." + format)
+	if err != nil {
+		log.Fatalf("Failed to create file, : %s", err)
+	}
+	defer file.Close()
+
+	err = writeFormat(tGraph, file, attrs)
+	if err != nil {
+		fmt.Printf("Error: %s", err.Error())
+	}
+}
+
 func exportCSV(dict dictInterface, fn string) {
 	folder := dict.getFolder()
 
@@ -585,11 +1012,10 @@ func exportCSV(dict dictInterface, fn string) {
 		{"source", "target"},
 	}
 
-	tGraph := &Graph{vertices: make(map[string]*Vertex)}
-	dict.AddData(tGraph)
+	tGraph := buildGraph(dict)
 
 	if fn != "" {
-		delNodes := getNodes(folder + fn)
+		delNodes := getNodes(folder+fn, dict.getKeyOptions())
 		for _, k := range delNodes {
 			tGraph.DeleteVertex(k)
 		}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// reads the raw WordNet database files into a WNdict without going through wrangle2.py
+// synsets are named like NLTK does (dog.n.01), and instead of Lesk every gloss token is
+// mapped to its most frequent synset that is headed by the token itself
+
+// file suffixes of the database files in the order senses are tried
+var wnFiles = []string{"noun", "verb", "adj", "adv"}
+
+// splits a gloss into words and punctuation, roughly like nltk's word_tokenize
+var wnTokens = regexp.MustCompile(`[A-Za-z0-9]+(?:['-][A-Za-z0-9]+)*|[^\sA-Za-z0-9]`)
+
+// a synset as read from a data file, before its ID is known
+type wnSynset struct {
+	offset string
+	ssType string
+	head   string
+	gloss  string
+}
+
+// Loads the WordNet database files in dir into memory
+func (wn *WNdict) loadDB(dir string) error {
+	// lemma -> synset offsets per file, in sense order
+	index := make(map[string]map[string][]string)
+	// offset -> synset per file
+	synsets := make(map[string]map[string]*wnSynset)
+
+	for _, f := range wnFiles {
+		idx, err := readWNIndex(filepath.Join(dir, "index."+f))
+		if err != nil {
+			return err
+		}
+		index[f] = idx
+
+		data, err := readWNData(filepath.Join(dir, "data."+f))
+		if err != nil {
+			return err
+		}
+		synsets[f] = data
+	}
+
+	// synset IDs are only known once the index has been read
+	IDs := make(map[string]map[string]string)
+	for _, f := range wnFiles {
+		IDs[f] = make(map[string]string)
+		for offset, ss := range synsets[f] {
+			IDs[f][offset] = wnID(ss, index[f][ss.head])
+		}
+	}
+
+	// first synset headed by the token itself
+	mapToken := func(tkn string) string {
+		for _, f := range wnFiles {
+			for _, offset := range index[f][tkn] {
+				ss, ok := synsets[f][offset]
+				if ok && ss.head == tkn {
+					return IDs[f][offset]
+				}
+			}
+		}
+		return ""
+	}
+
+	// phrase synsets like ice_cream.n.01, only kept if asked for
+	var phrases *phraseMatcher
+	if wn.phrases {
+		var names []string
+		for _, f := range wnFiles {
+			for _, ss := range synsets[f] {
+				names = append(names, phraseKey(ss.head))
+			}
+		}
+		phrases = newPhraseMatcher(names)
+	}
+
+	for _, f := range wnFiles {
+		for offset, ss := range synsets[f] {
+			if phrases == nil && strings.Contains(ss.head, "_") {
+				continue
+			}
+
+			tkns := wnTokens.FindAllString(ss.gloss, -1)
+
+			var regexTkns []string
+			var regexWords []string
+			var mappings []string
+
+			for i := 0; i < len(tkns); {
+				// longest phrase first, phrases are looked up with underscores
+				n := 1
+				if phrases != nil {
+					n = phrases.span(tkns, i)
+				}
+				tkn := strings.Join(tkns[i:i+n], "_")
+				i += n
+
+				ID := mapToken(tkn)
+				if ID == "" {
+					regexTkns = append(regexTkns, tkns[i-n:i]...)
+					continue
+				}
+				regexTkns = append(regexTkns, "%s")
+				regexWords = append(regexWords, phraseKey(tkn))
+				mappings = append(mappings, ID)
+			}
+
+			ID := IDs[f][offset]
+
+			def := &WNdef{ID: ID, name: phraseKey(ss.head), origDef: ss.gloss, regexDef: strings.Join(regexTkns, " "), regexWords: regexWords, mappings: mappings}
+
+			wn.addDef(ID, def)
+		}
+	}
+
+	return nil
+}
+
+// NLTK style ID of ss given the offsets of its first lemma in sense order
+func wnID(ss *wnSynset, offsets []string) string {
+	sense := 0
+	for i, offset := range offsets {
+		if offset == ss.offset {
+			sense = i + 1
+			break
+		}
+	}
+
+	return fmt.Sprintf("%s.%s.%02d", ss.head, ss.ssType, sense)
+}
+
+// Helper Function : loadDB
+// reads an index file into lemma -> synset offsets
+func readWNIndex(fn string) (map[string][]string, error) {
+	index := make(map[string][]string)
+
+	err := readWNLines(fn, func(fields []string) error {
+		// lemma pos synset_cnt p_cnt [ptr_symbol...] sense_cnt tagsense_cnt synset_offset...
+		if len(fields) < 4 {
+			return fmt.Errorf("short index line for %q", fields[0])
+		}
+		synsetCnt, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return err
+		}
+		pCnt, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return err
+		}
+		start := 4 + pCnt + 2
+		if len(fields) < start+synsetCnt {
+			return fmt.Errorf("short index line for %q", fields[0])
+		}
+		index[fields[0]] = fields[start : start+synsetCnt]
+		return nil
+	})
+
+	return index, err
+}
+
+// Helper Function : loadDB
+// reads a data file into offset -> synset
+func readWNData(fn string) (map[string]*wnSynset, error) {
+	synsets := make(map[string]*wnSynset)
+
+	err := readWNLines(fn, func(fields []string) error {
+		// synset_offset lex_filenum ss_type w_cnt word lex_id [word lex_id...] ... | gloss
+		if len(fields) < 5 {
+			return fmt.Errorf("short data line at %s", fields[0])
+		}
+
+		head := strings.ToLower(fields[4])
+		// adjective markers like (a), (p) and (ip)
+		if i := strings.Index(head, "("); i > 0 {
+			head = head[:i]
+		}
+
+		var gloss string
+		for i, f := range fields {
+			if f == "|" {
+				gloss = wnDefinition(strings.Join(fields[i+1:], " "))
+				break
+			}
+		}
+
+		synsets[fields[0]] = &wnSynset{offset: fields[0], ssType: fields[2], head: head, gloss: gloss}
+		return nil
+	})
+
+	return synsets, err
+}
+
+// strips the quoted examples from a gloss, the way NLTK's definition() does
+func wnDefinition(gloss string) string {
+	var defs []string
+
+	for _, part := range strings.Split(gloss, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.HasPrefix(part, `"`) {
+			continue
+		}
+		defs = append(defs, part)
+	}
+
+	return strings.Join(defs, "; ")
+}
+
+// Helper Function : loadDB
+// calls fn with the fields of every line that isn't part of the license header
+func readWNLines(fn string, fields func([]string) error) error {
+	file, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "  ") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := fields(strings.Fields(line)); err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	return scanner.Err()
+}
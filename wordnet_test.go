@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testdata/wordnet/ is a few hand written lines in the WordNet 3.0 database format
+
+func TestReadWNIndex(t *testing.T) {
+	index, err := readWNIndex("testdata/wordnet/index.noun")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pointer symbols are skipped, offsets stay in sense order
+	want := map[string][]string{
+		"dog":          {"02084071", "10114209"},
+		"domestic_dog": {"02084071"},
+		"entity":       {"00001740"},
+		"food":         {"07566340"},
+	}
+	if !reflect.DeepEqual(index, want) {
+		t.Errorf("index = %v, want %v", index, want)
+	}
+}
+
+func TestReadWNData(t *testing.T) {
+	synsets, err := readWNData("testdata/wordnet/data.adj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]wnSynset{
+		// the (a) marker is not part of the word
+		"01074650": {offset: "01074650", ssType: "a", head: "dry", gloss: "free from liquid or moisture"},
+		"01075178": {offset: "01075178", ssType: "s", head: "arid", gloss: "lacking sufficient water or rainfall"},
+		"01076000": {offset: "01076000", ssType: "s", head: "dry", gloss: "lacking moisture"},
+	}
+	if len(synsets) != len(want) {
+		t.Errorf("read %d synsets, want %d", len(synsets), len(want))
+	}
+	for offset, ss := range want {
+		if got, ok := synsets[offset]; !ok || *got != ss {
+			t.Errorf("synset %s = %+v, want %+v", offset, got, ss)
+		}
+	}
+
+	nouns, err := readWNData("testdata/wordnet/data.noun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every quoted example is dropped from the gloss
+	if gloss := nouns["10114209"].gloss; gloss != "a dull unattractive unpleasant girl or woman" {
+		t.Errorf("gloss = %q", gloss)
+	}
+}
+
+func TestWNID(t *testing.T) {
+	index, err := readWNIndex("testdata/wordnet/index.adj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	synsets, err := readWNData("testdata/wordnet/data.adj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// senses are numbered by the head's index line, satellites keep their s
+	want := map[string]string{
+		"01074650": "dry.a.01",
+		"01075178": "arid.s.01",
+		"01076000": "dry.s.02",
+	}
+	for offset, ID := range want {
+		ss := synsets[offset]
+		if got := wnID(ss, index[ss.head]); got != ID {
+			t.Errorf("wnID(%s) = %s, want %s", offset, got, ID)
+		}
+	}
+}
+
+func TestLoadDB(t *testing.T) {
+	wn := &WNdict{definitions: make(map[string][]*WNdef), IDMappings: make(map[string]*WNdef)}
+
+	if err := wn.loadDB("testdata/wordnet"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ID := range []string{"dog.n.01", "dog.n.02", "entity.n.01", "food.n.01", "eat.v.01", "dry.a.01", "dry.s.02", "arid.s.01", "quickly.r.01"} {
+		if _, ok := wn.IDMappings[ID]; !ok {
+			t.Errorf("no synset %s", ID)
+		}
+	}
+
+	// gloss words are mapped to the first synset they head
+	eat := wn.IDMappings["eat.v.01"]
+	if eat.regexDef != "take in solid %s" || !reflect.DeepEqual(eat.mappings, []string{"food.n.01"}) {
+		t.Errorf("eat.v.01 = %q %v", eat.regexDef, eat.mappings)
+	}
+	if n := len(wn.definitions["dog"]); n != 2 {
+		t.Errorf("dog has %d synsets, want 2", n)
+	}
+}
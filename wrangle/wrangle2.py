@@ -3,6 +3,9 @@ from nltk.tokenize import word_tokenize
 from nltk.corpus import wordnet
 import json
 
+# keep multi-word lemmas like ice_cream as "ice cream" and match them in definitions
+PHRASES = False
+
 dict = {}
 
 allWords = [n for n in wordnet.all_synsets()]
@@ -11,27 +14,39 @@ for word in allWords:
     ID = word.name()
     name = ID.split('.')[0]
     if '_' in name:
-        continue
+        if not PHRASES:
+            continue
+        name = name.replace('_', ' ')
     origDef = word.definition()
     regexDef = word.definition()
     regexWords = []
     Mappings = []
 
     tkns = word_tokenize(origDef)
-    regTkns = word_tokenize(origDef)
+    regTkns = []
+
+    i = 0
+    while i < len(tkns):
+        # longest phrase first
+        n = 1
+        if PHRASES:
+            for j in range(min(4, len(tkns) - i), 1, -1):
+                if wordnet.synsets('_'.join(tkns[i:i+j])):
+                    n = j
+                    break
+        tkn = '_'.join(tkns[i:i+n])
+        i += n
 
-    for i in range(len(tkns)):
-        tkn = tkns[i]
         c = lesk(origDef, tkn)
         if c is None:
-            continue
-        elif '_' in c.name().split('.')[0]:
-            continue
+            regTkns.extend(tkns[i-n:i])
+        elif '_' in c.name().split('.')[0] and not PHRASES:
+            regTkns.extend(tkns[i-n:i])
         elif tkn != c.name().split('.')[0]:
-            continue
+            regTkns.extend(tkns[i-n:i])
         else:
-            regTkns[i] = "%s"
-            regexWords.append(tkn)
+            regTkns.append("%s")
+            regexWords.append(tkn.replace('_', ' '))
             Mappings.append(c.name())
 
     regexDef = ' '.join(regTkns)